@@ -6,137 +6,259 @@ package bencode
 import (
 	"errors"
 	"fmt"
+	"math/big"
 	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 )
 
-// mapToStruct set decoded bencode data (map) to a struct by matching struct fields with "bencode" tags.
-func mapToStruct(val reflect.Value, decodedMap *interface{}, v any) error {
-	for i := 0; i < val.NumField(); i++ {
-		f := val.Type().Field(i)
+// Marshaler is the interface implemented by types that can marshal themselves into valid bencode.
+type Marshaler interface {
+	MarshalBencode() ([]byte, error)
+}
 
-		// Get bencode field and use it as key in decodedMap to find its value
-		bencodeField := f.Tag.Get("bencode")
-		if bencodeField == "" {
-			continue
-		}
-		decodedVal, ok := (*decodedMap).(map[string]interface{})[bencodeField]
-		if !ok {
-			continue
-		}
+// Unmarshaler is the interface implemented by types that can unmarshal a bencode representation of themselves. The
+// given data is a single, complete bencoded value, which must be copied if retained after returning.
+type Unmarshaler interface {
+	UnmarshalBencode([]byte) error
+}
 
-		structField := val.Field(i)
-		if structField.Kind() == reflect.Struct { // recursively convert map fields if value is struct
-			err := mapToStruct(structField, &decodedVal, v)
-			if err != nil {
-				return err
-			}
-			continue
-		}
-		if structField.CanSet() { // set value to given field
-			if !reflect.TypeOf(decodedVal).AssignableTo(structField.Type()) {
-				return fmt.Errorf(
-					"cannot assign decoded value to field '%s': expected %s, got %s",
-					bencodeField,
-					structField.Type(),
-					reflect.TypeOf(decodedVal),
-				)
-			}
-			structField.Set(reflect.ValueOf(decodedVal))
-		}
+// RawMessage is a raw encoded bencode value. It implements Marshaler and Unmarshaler, so it can be used to delay
+// the decoding of a value or to preserve its exact bytes, e.g. the info dictionary of a torrent.
+type RawMessage []byte
+
+// MarshalBencode returns m as the bencode encoding of m.
+func (m RawMessage) MarshalBencode() ([]byte, error) {
+	return m, nil
+}
+
+// UnmarshalBencode sets *m to a copy of data.
+func (m *RawMessage) UnmarshalBencode(data []byte) error {
+	if m == nil {
+		return errors.New("cannot unmarshal into nil RawMessage")
 	}
+	*m = append((*m)[0:0], data...)
 	return nil
 }
 
-// structToMap converts a struct to a map using the "bencode" tags as keys.
-func structToMap(v any) (map[string]interface{}, error) {
-	// Check if v is a struct
-	elem := reflect.ValueOf(v)
-	if elem.Kind() == reflect.Ptr {
-		elem = elem.Elem()
+// timeType is the reflection type of time.Time, which is encoded as a bencode integer of Unix seconds.
+var timeType = reflect.TypeOf(time.Time{})
+
+// bigIntType is the reflection type of big.Int, which holds bencode integers of arbitrary size.
+var bigIntType = reflect.TypeOf(big.Int{})
+
+// field holds the information of a struct field mapped to a bencode dictionary key.
+type field struct {
+	// name is the dictionary key, given by the field's "bencode" tag.
+	name string
+	// index is the path of struct indexes leading to the field, which is longer than one for inline fields.
+	index []int
+	// omitEmpty skips the field when marshalling if it holds an empty value.
+	omitEmpty bool
+	// required makes unmarshalling fail if the key is missing from the dictionary.
+	required bool
+}
+
+// structFields holds the bencode fields of a struct type, in declaration order and indexed by key.
+type structFields struct {
+	// list holds the fields in declaration order, with inline fields following the parent struct ones.
+	list []field
+	// byName maps each dictionary key to the position of its field in list.
+	byName map[string]int
+	// sorted holds the positions of the fields in list, sorted by key, which is the order they are encoded in.
+	sorted []int
+}
+
+// tagOptions holds the comma-separated options following the key in a "bencode" tag.
+type tagOptions string
+
+// parseTag splits a "bencode" tag into its key and options.
+func parseTag(tag string) (string, tagOptions) {
+	name, opts, _ := strings.Cut(tag, ",")
+	return name, tagOptions(opts)
+}
+
+// contains reports whether the comma-separated options include opt.
+func (o tagOptions) contains(opt string) bool {
+	for s := string(o); s != ""; {
+		var name string
+		name, s, _ = strings.Cut(s, ",")
+		if name == opt {
+			return true
+		}
 	}
-	if elem.Kind() != reflect.Struct {
-		return nil, errors.New("cannot convert to map: v is not a struct")
+	return false
+}
+
+// fieldCache caches the structFields of each struct type, as map[reflect.Type]structFields.
+var fieldCache sync.Map
+
+// cachedFields returns the bencode fields of the struct type t, computing them on first use.
+func cachedFields(t reflect.Type) structFields {
+	if f, ok := fieldCache.Load(t); ok {
+		return f.(structFields)
 	}
+	f, _ := fieldCache.LoadOrStore(t, typeFields(t))
+	return f.(structFields)
+}
 
-	mappedStruct := make(map[string]interface{})
-	for i := 0; i < elem.NumField(); i++ {
-		var val interface{}
-		f := elem.Type().Field(i)
+// typeFields returns the bencode fields of the struct type t. Fields tagged "-" or without a tag are skipped, unless
+// they are embedded structs without tag or are tagged with the "inline" option, in which case their own fields are
+// flattened into t. When several fields share the same key, the least nested one is kept.
+func typeFields(t reflect.Type) structFields {
+	// inlined holds a struct type whose fields are flattened into t, with the index path leading to it.
+	type inlined struct {
+		typ   reflect.Type
+		index []int
+	}
 
-		// Get bencode tag to use as key
-		key := f.Tag.Get("bencode")
-		if key == "" {
-			continue
-		}
+	fields := structFields{byName: make(map[string]int)}
+	visited := make(map[reflect.Type]bool)
+	for current := []inlined{{typ: t}}; len(current) > 0; { // breadth-first, by nesting level
+		var next []inlined
+		level := make(map[string]bool)
+		for _, s := range current {
+			if visited[s.typ] {
+				continue
+			}
+			visited[s.typ] = true
+
+			for i := 0; i < s.typ.NumField(); i++ {
+				sf := s.typ.Field(i)
+				tag := sf.Tag.Get("bencode")
+				if tag == "-" {
+					continue
+				}
+				name, opts := parseTag(tag)
+				index := append(append(make([]int, 0, len(s.index)+1), s.index...), i)
 
-		// Set values
-		structField := elem.Field(i)
-		if structField.Kind() == reflect.Struct { // recursively convert nested structs to map
-			var err error
-			val, err = structToMap(structField.Interface())
-			if err != nil {
-				return nil, fmt.Errorf("cannot convert struct '%s' to map: %w", key, err)
+				// Inline structs
+				ft := sf.Type
+				if ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				if ((sf.Anonymous && tag == "") || opts.contains("inline")) && ft.Kind() == reflect.Struct {
+					if sf.IsExported() || sf.Type.Kind() != reflect.Ptr { // unexported pointers cannot be allocated
+						next = append(next, inlined{typ: ft, index: index})
+					}
+					continue
+				}
+
+				// Keep only the least nested field of each key
+				if name == "" || !sf.IsExported() {
+					continue
+				}
+				if _, ok := fields.byName[name]; ok || level[name] {
+					continue
+				}
+				level[name] = true
+				fields.list = append(fields.list, field{
+					name:      name,
+					index:     index,
+					omitEmpty: opts.contains("omitempty"),
+					required:  opts.contains("required"),
+				})
 			}
-		} else {
-			val = structField.Interface()
 		}
-		mappedStruct[key] = val
+
+		for i := len(fields.byName); i < len(fields.list); i++ {
+			fields.byName[fields.list[i].name] = i
+		}
+		current = next
 	}
 
-	return mappedStruct, nil
+	fields.sorted = make([]int, len(fields.list))
+	for i := range fields.sorted {
+		fields.sorted[i] = i
+	}
+	slices.SortFunc(fields.sorted, func(a, b int) int {
+		return strings.Compare(fields.list[a].name, fields.list[b].name)
+	})
+	return fields
 }
 
-// Unmarshal decodes bencoded data into the structure or variable provided by v, which must be a pointer.
-func Unmarshal(data []byte, v any) error {
-	// Decode bencode
-	decoded, err := Decode(data)
-	if err != nil {
-		return err
+// fieldByIndex returns the field of the struct v at the given index path, allocating nil inline struct pointers.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
 	}
+	return v
+}
 
-	// Check if v is a pointer
-	obj := reflect.TypeOf(v)
-	if obj.Kind() != reflect.Ptr {
-		return errors.New("unmarshalling failed: v must be a pointer")
+// isEmptyValue reports whether v is empty for the "omitempty" option: false, 0, a zero time.Time, a nil pointer or
+// interface, and an empty string, slice, array or map.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return v.Len() == 0
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Ptr, reflect.Interface:
+		return v.IsZero()
+	case reflect.Struct:
+		return v.Type() == timeType && v.Interface().(time.Time).IsZero()
+	default:
+		return false
 	}
-	obj = obj.Elem()
+}
 
-	// If struct, set its fields value with decoded bencode, by "bencode" tag
-	if obj.Kind() == reflect.Struct {
-		val := reflect.ValueOf(v).Elem()
-		return mapToStruct(val, &decoded, val)
-	}
+// marshalerType is the reflection type of the Marshaler interface.
+var marshalerType = reflect.TypeOf((*Marshaler)(nil)).Elem()
 
-	// Otherwise, set decoded value
-	if obj.Kind() != reflect.TypeOf(decoded).Kind() {
-		return errors.New("unmarshalling failed: v is not the same type as the decoded value")
+// marshaler returns the Marshaler implemented by v, or by its address when v is addressable.
+func marshaler(v reflect.Value) (Marshaler, bool) {
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil, false
 	}
-	reflect.ValueOf(v).Elem().Set(reflect.ValueOf(decoded)) // update by reflection
-	return nil
+	if v.Type().Implements(marshalerType) {
+		return v.Interface().(Marshaler), true
+	}
+	if v.CanAddr() && reflect.PointerTo(v.Type()).Implements(marshalerType) {
+		return v.Addr().Interface().(Marshaler), true
+	}
+	return nil, false
 }
 
-// Marshal encodes the given value into a byte slice using with bencode format.
-func Marshal(v any) ([]byte, error) {
+// Unmarshal decodes bencoded data into the structure or variable provided by v, which must be a pointer. Values are
+// converted to the destination type: integers to any integer kind (with overflow checks), big.Int, bool or time.Time,
+// strings to string, byte slices or byte arrays, lists to slices or arrays, and dictionaries to maps with string
+// keys or structs, whose fields are matched by their "bencode" tags. Nil pointers are allocated as needed. Values of
+// keys not declared by a struct are skipped without being decoded or copied.
+func Unmarshal(data []byte, v any) error {
+	return unmarshal(newReader(data), v)
+}
+
+// unmarshal decodes the value at the current position of r into v, which must be a pointer.
+func unmarshal(r *bReader, v any) error {
 	// Check if v is a pointer
-	obj := reflect.TypeOf(v)
-	if obj.Kind() == reflect.Ptr {
-		obj = obj.Elem()
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Ptr || val.IsNil() {
+		return errors.New("unmarshalling failed: v must be a non-nil pointer")
 	}
 
-	// If v is a struct, map its values with "bencode" tag as keys
-	var val any
-	if obj.Kind() == reflect.Struct {
-		var err error
-		val, err = structToMap(v)
-		if err != nil {
-			return nil, fmt.Errorf("marshalling failed: %w", err)
-		}
-	} else {
-		val = v
+	err := r.checkInputSize()
+	if err == nil {
+		err = r.unmarshal(val)
+	}
+	if err != nil {
+		return fmt.Errorf("could not decode bencode: %w", err)
 	}
+	return nil
+}
 
-	// Encode
-	encoded, err := Encode(val)
+// Marshal encodes the given value into a byte slice using bencode format. Structs are encoded as dictionaries
+// whose keys are given by their fields "bencode" tags, honouring the "omitempty" and "inline" options; fields holding
+// nil pointers or interfaces are omitted. See Encode for the other supported types.
+func Marshal(v any) ([]byte, error) {
+	encoded, err := Encode(v)
 	if err != nil {
 		return nil, fmt.Errorf("marshalling failed: %w", err)
 	}
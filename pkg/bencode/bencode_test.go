@@ -1,9 +1,11 @@
 package bencode
 
 import (
+	"errors"
 	"flag"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/brianvoe/gofakeit/v7"
 	"github.com/stretchr/testify/assert"
@@ -15,7 +17,7 @@ const (
 	Str int = iota
 	Int
 	List
-	Dict
+	Dictionary
 )
 
 type testData[T interface{}] struct {
@@ -80,3 +82,355 @@ func TestMarshalAndUnmarshal(t *testing.T) {
 		}
 	}
 }
+
+type TestUnmarshalTypedFile struct {
+	Length int64    `bencode:"length"`
+	Path   []string `bencode:"path"`
+}
+
+type TestUnmarshalTypedData struct {
+	Int8     int8                              `bencode:"int8"`
+	Uint32   uint32                            `bencode:"uint32"`
+	Int64    int64                             `bencode:"int64"`
+	Bool     bool                              `bencode:"bool"`
+	Bytes    []byte                            `bencode:"bytes"`
+	Hash     [4]byte                           `bencode:"hash"`
+	Pointer  *string                           `bencode:"pointer"`
+	Time     time.Time                         `bencode:"time"`
+	Files    []TestUnmarshalTypedFile          `bencode:"files"`
+	FilesMap map[string]TestUnmarshalTypedFile `bencode:"files map"`
+	Private  string
+}
+
+func TestUnmarshalTyped(t *testing.T) {
+	t.Parallel()
+	for range *SimNumbers {
+		seed := gofakeit.Int64()
+		if gofakeit.Seed(seed) != nil {
+			continue
+		}
+
+		// Data
+		word := gofakeit.Word()
+		files := make([]TestUnmarshalTypedFile, gofakeit.IntN(5))
+		filesMap := make(map[string]TestUnmarshalTypedFile, len(files))
+		filesBCode := ""
+		filesMapBCode := ""
+		for i := range files {
+			files[i] = TestUnmarshalTypedFile{Length: gofakeit.Int64(), Path: []string{gofakeit.Word()}}
+			filesMap[strconv.Itoa(i)] = files[i]
+			fileBCode := "d6:lengthi" + strconv.FormatInt(files[i].Length, 10) + "e4:pathl" +
+				strconv.Itoa(len(files[i].Path[0])) + ":" + files[i].Path[0] + "ee"
+			filesBCode += fileBCode
+			filesMapBCode += "1:" + strconv.Itoa(i) + fileBCode
+		}
+		expected := TestUnmarshalTypedData{
+			Int8:     gofakeit.Int8(),
+			Uint32:   gofakeit.Uint32(),
+			Int64:    gofakeit.Int64(),
+			Bool:     gofakeit.Bool(),
+			Bytes:    []byte(word),
+			Hash:     [4]byte([]byte(gofakeit.LetterN(4))),
+			Pointer:  &word,
+			Time:     time.Unix(gofakeit.Int64()%1e10, 0),
+			Files:    files,
+			FilesMap: filesMap,
+		}
+		boolBCode := "i0e"
+		if expected.Bool {
+			boolBCode = "i1e"
+		}
+		bCode := "d4:booli" + boolBCode[1:] +
+			"5:bytes" + strconv.Itoa(len(word)) + ":" + word +
+			"5:filesl" + filesBCode + "e" +
+			"9:files mapd" + filesMapBCode + "e" +
+			"4:hash4:" + string(expected.Hash[:]) +
+			"5:int64i" + strconv.FormatInt(expected.Int64, 10) + "e" +
+			"4:int8i" + strconv.Itoa(int(expected.Int8)) + "e" +
+			"7:pointer" + strconv.Itoa(len(word)) + ":" + word +
+			"4:timei" + strconv.FormatInt(expected.Time.Unix(), 10) + "e" +
+			"6:uint32i" + strconv.FormatUint(uint64(expected.Uint32), 10) + "e" +
+			"7:unknownli1ei2eee"
+
+		// Tests
+		unmarshalled := TestUnmarshalTypedData{}
+		err := Unmarshal([]byte(bCode), &unmarshalled)
+		if assert.NoError(t, err, FormatInfo(seed, bCode)) {
+			assert.Equal(t, expected, unmarshalled, FormatInfo(seed, bCode))
+		}
+	}
+}
+
+func TestUnmarshalTypedErrors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		bCode string
+		v     any
+	}{
+		{"i128e", new(int8)},
+		{"i-1e", new(uint)},
+		{"i4294967296e", new(uint32)},
+		{"3:abc", new([4]byte)},
+		{"3:abc", new(int)},
+		{"i1e", new(string)},
+		{"le", new(map[string]int)},
+		{"de", new([]int)},
+		{"li1ei2ee", new([1]int)},
+		{"d1:ai1ee", new(map[int]int)},
+	}
+
+	for _, test := range tests {
+		assert.Error(t, Unmarshal([]byte(test.bCode), test.v), test.bCode)
+	}
+	assert.Error(t, Unmarshal([]byte("i1e"), 1))
+}
+
+type TestCompactPeer struct {
+	IP   [4]byte
+	Port uint16
+}
+
+type TestCompactPeers []TestCompactPeer
+
+func (p TestCompactPeers) MarshalBencode() ([]byte, error) {
+	compact := make([]byte, 0, len(p)*6)
+	for _, peer := range p {
+		compact = append(compact, peer.IP[:]...)
+		compact = append(compact, byte(peer.Port>>8), byte(peer.Port))
+	}
+	return Encode(string(compact))
+}
+
+func (p *TestCompactPeers) UnmarshalBencode(data []byte) error {
+	var compact []byte
+	if err := Unmarshal(data, &compact); err != nil {
+		return err
+	}
+	if len(compact)%6 != 0 {
+		return errors.New("malformed compact peers")
+	}
+
+	*p = make(TestCompactPeers, len(compact)/6)
+	for i := range *p {
+		(*p)[i].IP = [4]byte(compact[i*6 : i*6+4])
+		(*p)[i].Port = uint16(compact[i*6+4])<<8 | uint16(compact[i*6+5])
+	}
+	return nil
+}
+
+type TestMarshalerData struct {
+	Interval int               `bencode:"interval"`
+	Peers    TestCompactPeers  `bencode:"peers"`
+	Peers6   *TestCompactPeers `bencode:"peers6"`
+}
+
+func TestMarshalerAndUnmarshaler(t *testing.T) {
+	t.Parallel()
+	for range *SimNumbers {
+		seed := gofakeit.Int64()
+		if gofakeit.Seed(seed) != nil {
+			continue
+		}
+
+		// Data
+		peers := make(TestCompactPeers, gofakeit.IntN(10))
+		compact := ""
+		for i := range peers {
+			peers[i] = TestCompactPeer{IP: [4]byte([]byte(gofakeit.LetterN(4))), Port: gofakeit.Uint16()}
+			compact += string(peers[i].IP[:]) + string([]byte{byte(peers[i].Port >> 8), byte(peers[i].Port)})
+		}
+		testMarshal := TestMarshalerData{Interval: gofakeit.IntN(3600), Peers: peers, Peers6: &peers}
+		testUnmarshal := TestMarshalerData{}
+		peersBCode := strconv.Itoa(len(compact)) + ":" + compact
+		expectedBCode := "d8:intervali" + strconv.Itoa(testMarshal.Interval) + "e" +
+			"5:peers" + peersBCode +
+			"6:peers6" + peersBCode + "e"
+
+		// Tests
+		bCode, errMarshal := Marshal(&testMarshal)
+		if assert.NoError(t, errMarshal, FormatSeed(seed)) && assert.Equal(t, expectedBCode, string(bCode)) {
+			errUnmarshal := Unmarshal(bCode, &testUnmarshal)
+			if assert.NoError(t, errUnmarshal, FormatInfo(seed, string(bCode))) {
+				assert.Equal(t, testMarshal, testUnmarshal, FormatInfo(seed, string(bCode)))
+			}
+		}
+	}
+}
+
+type TestRawMessageData struct {
+	Announce string     `bencode:"announce"`
+	Info     RawMessage `bencode:"info"`
+}
+
+func TestRawMessage(t *testing.T) {
+	t.Parallel()
+	for range *SimNumbers {
+		seed := gofakeit.Int64()
+		if gofakeit.Seed(seed) != nil {
+			continue
+		}
+
+		// Info dictionary with unsorted keys, which must be preserved as is
+		announce := genStringEncodeTest()
+		private := genIntEncodeTest()
+		name := genStringEncodeTest()
+		info := "d7:privatei" + strconv.Itoa(private.data) + "e4:name" + name.bCode + "e"
+		bCode := "d8:announce" + announce.bCode + "4:info" + info + "e"
+
+		// Tests
+		unmarshalled := TestRawMessageData{}
+		err := Unmarshal([]byte(bCode), &unmarshalled)
+		if assert.NoError(t, err, FormatInfo(seed, bCode)) {
+			assert.Equal(t, info, string(unmarshalled.Info), FormatInfo(seed, bCode))
+			marshalled, err := Marshal(&unmarshalled)
+			if assert.NoError(t, err, FormatInfo(seed, bCode)) {
+				assert.Equal(t, bCode, string(marshalled), FormatSeed(seed))
+			}
+		}
+	}
+}
+
+func TestInvalidRawMessage(t *testing.T) {
+	t.Parallel()
+	for _, bCode := range []string{"d3:fooe", "di1ei2ee", "i--e", "i01e", "l+1:ae", "l1:a", "e", ""} {
+		_, err := Encode(RawMessage(bCode))
+		assert.ErrorContains(t, err, "MarshalBencode returned invalid bencode", bCode)
+
+		var raw RawMessage
+		assert.Error(t, Unmarshal([]byte(bCode), &raw), bCode)
+	}
+	_, err := Encode(RawMessage("i1ei2e"))
+	assert.ErrorContains(t, err, "MarshalBencode returned invalid bencode")
+}
+
+type TestTagOptionsCommon struct {
+	Name    string `bencode:"name,required"`
+	Comment string `bencode:"comment,omitempty"`
+}
+
+type TestTagOptionsExtra struct {
+	Source string `bencode:"source,omitempty"`
+}
+
+type TestTagOptionsData struct {
+	TestTagOptionsCommon
+	Extra   *TestTagOptionsExtra `bencode:",inline"`
+	Length  int                  `bencode:"length,omitempty"`
+	Files   []string             `bencode:"files,omitempty"`
+	Created time.Time            `bencode:"created,omitempty"`
+	Ignored string               `bencode:"-"`
+	Dash    string               `bencode:"-,"`
+}
+
+func TestTagOptions(t *testing.T) {
+	t.Parallel()
+	for range *SimNumbers {
+		seed := gofakeit.Int64()
+		if gofakeit.Seed(seed) != nil {
+			continue
+		}
+
+		// Data
+		name := genStringEncodeTest()
+		dash := genStringEncodeTest()
+		source := genStringEncodeTest()
+		length := gofakeit.IntN(2)
+		testMarshal := TestTagOptionsData{
+			TestTagOptionsCommon: TestTagOptionsCommon{Name: name.data},
+			Extra:                &TestTagOptionsExtra{Source: source.data},
+			Length:               length,
+			Ignored:              gofakeit.Word(),
+			Dash:                 dash.data,
+		}
+		expectedBCode := "d1:-" + dash.bCode
+		if length != 0 {
+			expectedBCode += "6:lengthi" + strconv.Itoa(length) + "e"
+		}
+		expectedBCode += "4:name" + name.bCode + "6:source" + source.bCode + "e"
+
+		// Tests
+		bCode, err := Marshal(&testMarshal)
+		if assert.NoError(t, err, FormatSeed(seed)) && assert.Equal(t, expectedBCode, string(bCode), FormatSeed(seed)) {
+			testUnmarshal := TestTagOptionsData{}
+			testMarshal.Ignored = ""
+			if assert.NoError(t, Unmarshal(bCode, &testUnmarshal), FormatInfo(seed, string(bCode))) {
+				assert.Equal(t, testMarshal, testUnmarshal, FormatInfo(seed, string(bCode)))
+			}
+		}
+	}
+}
+
+func TestTagOptionsRequired(t *testing.T) {
+	t.Parallel()
+	testUnmarshal := TestTagOptionsData{}
+	assert.Error(t, Unmarshal([]byte("d6:lengthi1ee"), &testUnmarshal))
+	assert.NoError(t, Unmarshal([]byte("d4:name0:e"), &testUnmarshal))
+}
+
+type TestPartialTorrent struct {
+	Announce string `bencode:"announce"`
+	Info     struct {
+		Name string `bencode:"name"`
+	} `bencode:"info"`
+}
+
+func TestUnmarshalSkipsUndeclaredKeys(t *testing.T) {
+	t.Parallel()
+	bCode := []byte("d8:announce3:url7:commentd1:ali1ee1:b0:e4:infod6:lengthi10e4:name4:test6:pieces2:xxee")
+	testUnmarshal := TestPartialTorrent{}
+	if assert.NoError(t, Unmarshal(bCode, &testUnmarshal)) {
+		assert.Equal(t, "url", testUnmarshal.Announce)
+		assert.Equal(t, "test", testUnmarshal.Info.Name)
+	}
+
+	// Undeclared values must still be valid bencode
+	for _, bCode := range []string{
+		"d7:commentli1x2ee8:announce3:urle",
+		"d7:commentd3:fooe8:announce3:urle",    // key without value
+		"d7:commentdi1ei2ee8:announce3:urle",   // integer key
+		"d7:commentli--ee8:announce3:urle",     // invalid integer
+		"d7:commentli-ee8:announce3:urle",      // integer without digits
+		"d7:commentli01ee8:announce3:urle",     // integer with leading 0
+		"d7:commentl+1:ae8:announce3:urle",     // string length with sign
+		"d7:commentd1:al-0:ee8:announce3:urle", // negative string length
+	} {
+		var syntaxErr *SyntaxError
+		err := Unmarshal([]byte(bCode), &testUnmarshal)
+		if assert.ErrorAs(t, err, &syntaxErr, bCode) {
+			assert.Equal(t, "comment", syntaxErr.Path, bCode)
+		}
+		_, err = Decode([]byte(bCode))
+		assert.ErrorAs(t, err, &syntaxErr, bCode)
+	}
+}
+
+func BenchmarkUnmarshalPartial(b *testing.B) {
+	// Multi-file torrent with a large pieces string and many undeclared keys
+	files := make([]map[string]interface{}, 1000)
+	for i := range files {
+		files[i] = map[string]interface{}{"length": i, "path": []interface{}{"dir", "file-" + strconv.Itoa(i)}}
+	}
+	bCode, err := Marshal(map[string]interface{}{
+		"announce":      "http://tracker.example.com/announce",
+		"comment":       "benchmark",
+		"creation date": 1700000000,
+		"info": map[string]interface{}{
+			"files":        files,
+			"name":         "benchmark",
+			"piece length": 256 << 10,
+			"pieces":       string(make([]byte, 20*16384)),
+		},
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		var torrent TestPartialTorrent
+		if err := Unmarshal(bCode, &torrent); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
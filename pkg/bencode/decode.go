@@ -1,22 +1,106 @@
 package bencode
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"math/big"
+	"reflect"
 	"strconv"
+	"time"
 )
 
+// Limits bounds the resources used to decode untrusted input, such as tracker responses and peer messages. A zero
+// field means no limit.
+type Limits struct {
+	// MaxDepth is the maximum nesting level of lists and dictionaries.
+	MaxDepth int
+	// MaxStringLength is the maximum length of a single string, in bytes.
+	MaxStringLength int
+	// MaxElements is the maximum number of values (integers, strings, lists and dictionaries) in a decoded document.
+	MaxElements int
+	// MaxInputSize is the maximum size of a single bencoded document, in bytes.
+	MaxInputSize int64
+}
+
+// DefaultLimits are the limits used by Decode, Unmarshal and new Decoders. Only the nesting depth is bounded, to
+// protect the stack from deeply nested input.
+var DefaultLimits = Limits{MaxDepth: 512}
+
 // bReader is a struct for reading and decoding bencoded data from a byte slice.
 type bReader struct {
 	// data holds the byte slice representing the bencoded data to be parsed and decoded.
 	data []byte
 	// pos holds the current reading position in the bencoded data slice.
 	pos int
+	// base holds the offset of data in the whole input, used to report error offsets.
+	base int64
+	// path holds the dictionary keys and list indexes leading to the value being decoded, used to report errors.
+	path []pathElem
+	// strict enables the canonical form checks, rejecting unsorted or duplicate dictionary keys, negative zero and
+	// leading zeros in string lengths.
+	strict bool
+	// limits bounds the resources used to decode data.
+	limits Limits
+	// useBigInt decodes generic integers beyond 64 bits as *big.Int, instead of failing.
+	useBigInt bool
+	// useDict decodes generic dictionaries as Dict, keeping their key order and duplicate keys.
+	useDict bool
+	// depth holds the current nesting level of lists and dictionaries.
+	depth int
+	// elements holds the number of values decoded so far.
+	elements int
 }
 
 // newReader initializes and returns a new bReader for reading and decoding bencoded data from the provided byte slice.
 func newReader(data []byte) *bReader {
-	return &bReader{data: data, pos: 0}
+	return &bReader{data: data, pos: 0, limits: DefaultLimits}
+}
+
+// limitError returns a *LimitError for the limit err exceeded at the given offset of the data, at the current path.
+func (r *bReader) limitError(err error, offset int, limit int64) error {
+	return &LimitError{Err: err, Limit: limit, Offset: r.base + int64(offset), Path: formatPath(r.path)}
+}
+
+// checkInputSize checks that the whole data does not exceed the maximum input size.
+func (r *bReader) checkInputSize() error {
+	if r.limits.MaxInputSize > 0 && int64(len(r.data)) > r.limits.MaxInputSize {
+		return r.limitError(ErrMaxInputSize, 0, r.limits.MaxInputSize)
+	}
+	return nil
+}
+
+// countElement counts a value about to be decoded, checking the maximum number of elements.
+func (r *bReader) countElement() error {
+	r.elements++
+	if r.limits.MaxElements > 0 && r.elements > r.limits.MaxElements {
+		return r.limitError(ErrMaxElements, r.pos, int64(r.limits.MaxElements))
+	}
+	return nil
+}
+
+// enter increases the nesting level when a list or dictionary starts, checking the maximum depth.
+func (r *bReader) enter() error {
+	r.depth++
+	if r.limits.MaxDepth > 0 && r.depth > r.limits.MaxDepth {
+		return r.limitError(ErrMaxDepth, r.pos, int64(r.limits.MaxDepth))
+	}
+	return nil
+}
+
+// syntaxError returns a *SyntaxError found at the given offset of the data, at the current path.
+func (r *bReader) syntaxError(offset int, expected string, msg string) error {
+	return &SyntaxError{Offset: r.base + int64(offset), Expected: expected, Path: formatPath(r.path), msg: msg}
+}
+
+// typeError returns an *UnmarshalTypeError for the value at the given offset of the data, at the current path.
+func (r *bReader) typeError(offset int, value string, t reflect.Type) error {
+	return &UnmarshalTypeError{Value: value, Type: t, Offset: r.base + int64(offset), Path: formatPath(r.path)}
+}
+
+// nonCanonicalError returns a *NonCanonicalError found at the given offset of the data, at the current path.
+func (r *bReader) nonCanonicalError(offset int, reason string) error {
+	return &NonCanonicalError{Offset: r.base + int64(offset), Path: formatPath(r.path), Reason: reason}
 }
 
 // readUntil reads bytes from the current position up to and including the specified delimiter.
@@ -30,137 +114,629 @@ func (r *bReader) readUntil(delim byte) ([]byte, error) {
 		}
 		r.pos++
 	}
-	return nil, errors.New("missing delimiter")
+	return nil, r.syntaxError(r.pos, fmt.Sprintf("'%c'", delim), "unexpected end of input")
+}
+
+// isDigits reports whether s is a non-empty sequence of decimal digits.
+func isDigits(s []byte) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return len(s) > 0
+}
+
+// validInt reports whether s holds the digits of a bencoded integer: an optional '-' followed by decimal digits, without
+// leading zeros, even after the sign.
+func validInt(s []byte) bool {
+	digits := s
+	if len(digits) > 0 && digits[0] == '-' {
+		digits = digits[1:]
+	}
+	return isDigits(digits) && (len(digits) == 1 || digits[0] != '0')
 }
 
-// decodeInt decodes a bencoded integer from the current position in the data and returns it as an int.
-func (r *bReader) decodeInt() (int, error) {
+// readInt reads a bencoded integer from the current position in the data and returns its digits.
+func (r *bReader) readInt() ([]byte, error) {
+	start := r.pos
 	s, err := r.readUntil('e')
 	if err != nil {
-		return 0, fmt.Errorf("could not decode integer: %w", err)
+		return nil, err
 	}
 
+	digits := s[1 : len(s)-1]
+	if len(digits) > 0 && digits[0] == '-' {
+		digits = digits[1:]
+	}
 	if len(s) == 2 {
-		return 0, fmt.Errorf("bencode integer '%s' is empty", s)
-	} else if len(s) > 3 && s[1] == '0' {
-		return 0, fmt.Errorf("bencode integer '%s' has leading 0", s)
+		return nil, r.syntaxError(start, "digits", fmt.Sprintf("bencode integer '%s' is empty", s))
+	} else if !isDigits(digits) {
+		return nil, r.syntaxError(start, "digits", fmt.Sprintf("bencode integer '%s' is invalid", s))
+	} else if len(digits) > 1 && digits[0] == '0' {
+		return nil, r.syntaxError(start, "", fmt.Sprintf("bencode integer '%s' has leading 0", s))
+	} else if r.strict && digits[0] == '0' && s[1] == '-' {
+		return nil, r.nonCanonicalError(start, fmt.Sprintf("integer '%s' is negative zero", s))
 	}
+	return s[1 : len(s)-1], nil
+}
 
-	integer, err := strconv.Atoi(string(s[1 : len(s)-1]))
+// decodeInt decodes a bencoded integer from the current position in the data and returns it as an int, or as an
+// int64 if it does not fit in an int. Integers beyond 64 bits are returned as *big.Int, if enabled by useBigInt.
+func (r *bReader) decodeInt() (interface{}, error) {
+	start := r.pos
+	s, err := r.readInt()
 	if err != nil {
-		return 0, fmt.Errorf("could not decode integer '%s': %w", s, err)
+		return nil, err
+	}
+
+	integer, err := strconv.ParseInt(string(s), 10, 64)
+	if err == nil {
+		if int64(int(integer)) == integer {
+			return int(integer), nil
+		}
+		return integer, nil
 	}
-	return integer, nil
+	if !r.useBigInt {
+		return nil, r.typeError(start, "integer "+string(s), reflect.TypeOf(integer))
+	}
+	n, _ := new(big.Int).SetString(string(s), 10)
+	return n, nil
 }
 
-// decodeString decodes a bencoded string from the current position in the data and returns it as a string.
-func (r *bReader) decodeString() (string, error) {
+// readString reads a bencoded string from the current position in the data and returns its content, without
+// copying it.
+func (r *bReader) readString() ([]byte, error) {
+	start := r.pos
 	lenStr, err := r.readUntil(':')
 	if err != nil {
-		return "", fmt.Errorf("could not decode string: %w", err)
+		return nil, err
 	}
 
-	length, err := strconv.Atoi(string(lenStr[:len(lenStr)-1]))
-	if err != nil {
-		return "", fmt.Errorf("could not decode string '%s' length: %w", r.data[:r.pos], err)
+	lenStr = lenStr[:len(lenStr)-1]
+	length, err := strconv.Atoi(string(lenStr))
+	if err != nil || !isDigits(lenStr) {
+		return nil, r.syntaxError(start, "string length", fmt.Sprintf("invalid string length '%s'", lenStr))
+	}
+	if r.strict && len(lenStr) > 1 && lenStr[0] == '0' {
+		return nil, r.nonCanonicalError(start, fmt.Sprintf("string length '%s' has leading 0", lenStr))
 	}
-	if length < 0 {
-		return "", fmt.Errorf("string length is negative: %d", length)
+	if r.limits.MaxStringLength > 0 && length > r.limits.MaxStringLength {
+		return nil, r.limitError(ErrMaxStringLength, start, int64(r.limits.MaxStringLength))
 	}
 
 	end := r.pos + length
-	if end > len(r.data) {
-		return "", fmt.Errorf("string length exceeds input length: expected %d bytes, got %d bytes", end, len(r.data))
+	if end > len(r.data) || end < r.pos {
+		remaining := len(r.data) - r.pos
+		msg := fmt.Sprintf("string length exceeds input length: expected %d bytes, got %d bytes", length, remaining)
+		return nil, r.syntaxError(len(r.data), "", msg)
 	}
 
-	start := r.pos
+	start = r.pos
 	r.pos = end
-	return string(r.data[start:end]), nil
+	return r.data[start:end], nil
+}
+
+// decodeString decodes a bencoded string from the current position in the data and returns it as a string.
+func (r *bReader) decodeString() (string, error) {
+	s, err := r.readString()
+	if err != nil {
+		return "", err
+	}
+	return string(s), nil
+}
+
+// readKey reads a dictionary key from the current position. In strict mode, keys must be sorted and unique, so the
+// key is compared with prev, the previous key of the same dictionary (nil for the first one).
+func (r *bReader) readKey(prev []byte) ([]byte, error) {
+	start := r.pos
+	if c := r.data[r.pos]; c < '0' || c > '9' {
+		return nil, r.syntaxError(start, "string key", fmt.Sprintf("invalid character %q in dictionary key", c))
+	}
+	key, err := r.readString()
+	if err != nil {
+		return nil, err
+	}
+
+	if r.strict && prev != nil {
+		if cmp := bytes.Compare(key, prev); cmp == 0 {
+			return nil, r.nonCanonicalError(start, fmt.Sprintf("duplicate dictionary key '%s'", key))
+		} else if cmp < 0 {
+			return nil, r.nonCanonicalError(start, fmt.Sprintf("dictionary key '%s' is not sorted", key))
+		}
+	}
+	return key, nil
 }
 
 // decodeList decodes a bencoded list from the current position in the data and returns it as a slice of interfaces.
 func (r *bReader) decodeList() ([]interface{}, error) {
+	if err := r.enter(); err != nil {
+		return nil, err
+	}
 	list := make([]interface{}, 0)
 	r.pos++
 
 	for {
 		if r.pos >= len(r.data) {
-			return nil, errors.New("eol reached before end of list")
+			return nil, r.syntaxError(r.pos, "'e'", "eol reached before end of list")
 		}
 
 		if r.data[r.pos] == 'e' {
 			break
 		}
 
+		r.path = append(r.path, pathElem{index: len(list)})
 		val, err := r.decodeElement() // decode list element
 		if err != nil {
 			return nil, err
 		}
+		r.path = r.path[:len(r.path)-1]
 
 		list = append(list, val)
 	}
 
 	r.pos++
+	r.depth--
 	return list, nil
 }
 
 // decodeDict decodes a bencoded dictionary from the current position in the data and returns it as a
-// map[string]interface{}.
-func (r *bReader) decodeDict() (map[string]interface{}, error) {
-	dict := make(map[string]interface{})
+// map[string]interface{}, or as a Dict if enabled by useDict.
+func (r *bReader) decodeDict() (interface{}, error) {
+	if err := r.enter(); err != nil {
+		return nil, err
+	}
+	var dict map[string]interface{}
+	var ordered Dict
+	if r.useDict {
+		ordered = Dict{}
+	} else {
+		dict = make(map[string]interface{})
+	}
 	r.pos++
 
+	var key []byte
 	for {
 		if r.pos >= len(r.data) {
-			return nil, errors.New("eol reached before end of dict")
+			return nil, r.syntaxError(r.pos, "'e'", "eol reached before end of dict")
 		}
 
 		if r.data[r.pos] == 'e' {
 			break
 		}
 
-		key, err := r.decodeString() // decode map key (must be a string)
+		var err error
+		key, err = r.readKey(key) // decode map key (must be a string)
 		if err != nil {
-			return nil, fmt.Errorf("could not decode dict key: %w", err)
+			return nil, err
 		}
 
+		r.path = append(r.path, pathElem{key: key})
 		val, err := r.decodeElement() // decode map value
 		if err != nil {
-			return nil, fmt.Errorf("could not decode dict value: %w", err)
+			return nil, err
 		}
+		r.path = r.path[:len(r.path)-1]
 
-		dict[key] = val
+		if r.useDict {
+			ordered = append(ordered, DictEntry{Key: string(key), Value: val})
+		} else {
+			dict[string(key)] = val
+		}
 	}
 
 	r.pos++
+	r.depth--
+	if r.useDict {
+		return ordered, nil
+	}
 	return dict, nil
 }
 
 // decodeElement decodes a generic bencoded element at the current position and returns it as an interface.
 func (r *bReader) decodeElement() (interface{}, error) {
 	if len(r.data[r.pos:]) == 0 {
-		return nil, errors.New("entry is empty")
+		return nil, r.syntaxError(r.pos, "value", "entry is empty")
+	}
+	if err := r.countElement(); err != nil {
+		return nil, err
 	}
 
 	switch firstChar := r.data[r.pos]; {
+	case r.useDict && (firstChar == 'i' || firstChar >= '0' && firstChar <= '9'):
+		return r.decodeDictScalar()
 	case firstChar == 'i':
 		return r.decodeInt()
 	case firstChar == 'l':
 		return r.decodeList()
 	case firstChar == 'd':
 		return r.decodeDict()
-	case firstChar >= '1' && firstChar <= '9':
+	case firstChar >= '0' && firstChar <= '9':
 		return r.decodeString()
 	default:
-		return nil, errors.New("invalid type encountered: character not 'i', 'l', 'd', or '1'-'9'")
+		return nil, r.syntaxError(r.pos, "'i', 'l', 'd', or '0'-'9'", fmt.Sprintf("invalid character %q", firstChar))
 	}
 }
 
+// decodeDictScalar decodes an integer or a string held by a Dict. Values that are not in canonical form, such as
+// "i-0e" or "03:abc", and integers beyond 64 bits, unless enabled by useBigInt, are kept as a RawMessage of their
+// original bytes, so they are re-encoded unchanged.
+func (r *bReader) decodeDictScalar() (interface{}, error) {
+	start := r.pos
+	var val interface{}
+	var err error
+	if r.data[r.pos] == 'i' {
+		val, err = r.decodeInt()
+		var typeErr *UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return RawMessage(bytes.Clone(r.data[start:r.pos])), nil
+		}
+	} else {
+		val, err = r.decodeString()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	raw := r.data[start:r.pos]
+	w := bWriter{}
+	if err = w.encode(reflect.ValueOf(val)); err != nil || !bytes.Equal(w.buf, raw) {
+		return RawMessage(bytes.Clone(raw)), nil
+	}
+	return val, nil
+}
+
+// skipElement advances over the element at the current position without decoding it, and returns its raw bytes.
+func (r *bReader) skipElement() ([]byte, error) {
+	if r.strict { // canonical form is only checked by decoding
+		start := r.pos
+		_, err := r.decodeElement()
+		if err != nil {
+			return nil, err
+		}
+		return r.data[start:r.pos], nil
+	}
+
+	scan := scanState{pos: r.pos, depth: r.depth, baseDepth: r.depth, limits: r.limits}
+	end, err := scan.scan(r.data)
+	var syntaxErr *SyntaxError
+	var limitErr *LimitError
+	if errors.Is(err, errIncomplete) {
+		return nil, r.syntaxError(len(r.data), "", "eol reached before end of element")
+	} else if errors.As(err, &syntaxErr) {
+		syntaxErr.Offset += r.base
+		syntaxErr.Path = formatPath(r.path)
+		return nil, syntaxErr
+	} else if errors.As(err, &limitErr) {
+		limitErr.Offset += r.base
+		limitErr.Path = formatPath(r.path)
+		return nil, limitErr
+	} else if err != nil {
+		return nil, err
+	}
+
+	r.elements += scan.elements
+	if r.limits.MaxElements > 0 && r.elements > r.limits.MaxElements {
+		return nil, r.limitError(ErrMaxElements, r.pos, int64(r.limits.MaxElements))
+	}
+
+	raw := r.data[r.pos:end]
+	r.pos = end
+	return raw, nil
+}
+
 // Decode parses a bencoded byte slice and returns the decoded value as an interface or an error, if decoding fails.
+// Integers are decoded as int, or as int64 if they do not fit in an int; strings as string; lists as []interface{}
+// and dictionaries as map[string]interface{}.
 func Decode(s []byte) (interface{}, error) {
-	val, err := newReader(s).decodeElement()
+	r := newReader(s)
+	err := r.checkInputSize()
+	if err != nil {
+		return nil, fmt.Errorf("could not decode bencode: %w", err)
+	}
+
+	val, err := r.decodeElement()
 	if err != nil {
 		return nil, fmt.Errorf("could not decode bencode: %w", err)
 	}
 	return val, nil
 }
+
+// indirect walks down v through pointers, allocating them when nil, until it finds an Unmarshaler or a
+// non-pointer value, which are returned.
+func indirect(v reflect.Value) (Unmarshaler, reflect.Value) {
+	for {
+		if v.Kind() != reflect.Ptr && v.CanAddr() { // check pointer receiver methods
+			if u, ok := v.Addr().Interface().(Unmarshaler); ok {
+				return u, v
+			}
+		}
+		if v.Kind() != reflect.Ptr {
+			return nil, v
+		}
+
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		if u, ok := v.Interface().(Unmarshaler); ok {
+			return u, v
+		}
+		v = v.Elem()
+	}
+}
+
+// unmarshal decodes the element at the current position directly into v, converting it to v's type.
+func (r *bReader) unmarshal(v reflect.Value) error {
+	if len(r.data[r.pos:]) == 0 {
+		return r.syntaxError(r.pos, "value", "entry is empty")
+	}
+
+	u, v := indirect(v)
+	if u != nil { // value unmarshals itself
+		raw, err := r.skipElement()
+		if err != nil {
+			return err
+		}
+		return u.UnmarshalBencode(raw)
+	}
+	if v.Kind() == reflect.Interface && v.NumMethod() == 0 { // generic value
+		val, err := r.decodeElement()
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(val))
+		return nil
+	}
+	if v.Type() == dictType { // ordered generic dictionary
+		return r.unmarshalOrderedDict(v)
+	}
+	if err := r.countElement(); err != nil {
+		return err
+	}
+
+	switch firstChar := r.data[r.pos]; {
+	case firstChar == 'i':
+		return r.unmarshalInt(v)
+	case firstChar == 'l':
+		return r.unmarshalList(v)
+	case firstChar == 'd':
+		return r.unmarshalDict(v)
+	case firstChar >= '0' && firstChar <= '9':
+		return r.unmarshalString(v)
+	default:
+		return r.syntaxError(r.pos, "'i', 'l', 'd', or '0'-'9'", fmt.Sprintf("invalid character %q", firstChar))
+	}
+}
+
+// unmarshalInt decodes a bencoded integer into v, which must be an integer, a big.Int, a bool or a time.Time (Unix
+// seconds).
+func (r *bReader) unmarshalInt(v reflect.Value) error {
+	start := r.pos
+	s, err := r.readInt()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case v.Kind() >= reflect.Int && v.Kind() <= reflect.Int64:
+		i, err := strconv.ParseInt(string(s), 10, v.Type().Bits())
+		if err != nil {
+			return r.typeError(start, "integer "+string(s), v.Type())
+		}
+		v.SetInt(i)
+	case v.Kind() >= reflect.Uint && v.Kind() <= reflect.Uintptr:
+		u, err := strconv.ParseUint(string(s), 10, v.Type().Bits())
+		if err != nil {
+			return r.typeError(start, "integer "+string(s), v.Type())
+		}
+		v.SetUint(u)
+	case v.Kind() == reflect.Bool:
+		v.SetBool(string(s) != "0" && string(s) != "-0")
+	case v.Type() == timeType:
+		i, err := strconv.ParseInt(string(s), 10, 64)
+		if err != nil {
+			return r.typeError(start, "integer "+string(s), v.Type())
+		}
+		v.Set(reflect.ValueOf(time.Unix(i, 0)))
+	case v.Type() == bigIntType && v.CanAddr():
+		v.Addr().Interface().(*big.Int).SetString(string(s), 10)
+	default:
+		return r.typeError(start, "integer", v.Type())
+	}
+	return nil
+}
+
+// unmarshalString decodes a bencoded string into v, which must be a string, a byte slice or a byte array of the
+// same length.
+func (r *bReader) unmarshalString(v reflect.Value) error {
+	start := r.pos
+	s, err := r.readString()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case v.Kind() == reflect.String:
+		v.SetString(string(s))
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		v.SetBytes(append(make([]byte, 0, len(s)), s...))
+	case v.Kind() == reflect.Array && v.Type().Elem().Kind() == reflect.Uint8:
+		if v.Len() != len(s) {
+			return r.typeError(start, "string of length "+strconv.Itoa(len(s)), v.Type())
+		}
+		reflect.Copy(v, reflect.ValueOf(s))
+	default:
+		return r.typeError(start, "string", v.Type())
+	}
+	return nil
+}
+
+// unmarshalList decodes a bencoded list into v, which must be a slice or an array, decoding each element into the
+// corresponding index.
+func (r *bReader) unmarshalList(v reflect.Value) error {
+	start := r.pos
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return r.typeError(start, "list", v.Type())
+	}
+	if err := r.enter(); err != nil {
+		return err
+	}
+	if v.Kind() == reflect.Slice {
+		v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+	}
+	r.pos++
+
+	i := 0
+	for ; ; i++ {
+		if r.pos >= len(r.data) {
+			return r.syntaxError(r.pos, "'e'", "eol reached before end of list")
+		}
+
+		if r.data[r.pos] == 'e' {
+			break
+		}
+
+		if v.Kind() == reflect.Slice {
+			v.Set(reflect.Append(v, reflect.Zero(v.Type().Elem())))
+		} else if i >= v.Len() {
+			return r.typeError(start, "list of more than "+strconv.Itoa(v.Len())+" elements", v.Type())
+		}
+
+		r.path = append(r.path, pathElem{index: i})
+		err := r.unmarshal(v.Index(i)) // decode list element
+		if err != nil {
+			return err
+		}
+		r.path = r.path[:len(r.path)-1]
+	}
+
+	// Zero remaining array elements
+	for ; v.Kind() == reflect.Array && i < v.Len(); i++ {
+		v.Index(i).Set(reflect.Zero(v.Type().Elem()))
+	}
+
+	r.pos++
+	r.depth--
+	return nil
+}
+
+// unmarshalDict decodes a bencoded dictionary into v, which must be a map with string keys or a struct, whose
+// fields are matched by their "bencode" tags.
+func (r *bReader) unmarshalDict(v reflect.Value) error {
+	start := r.pos
+	var fields structFields
+	var seen []bool // whether each struct field was found
+	switch {
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+	case v.Kind() == reflect.Struct && v.Type() != timeType:
+		fields = cachedFields(v.Type())
+		seen = make([]bool, len(fields.list))
+	default:
+		return r.typeError(start, "dictionary", v.Type())
+	}
+	if err := r.enter(); err != nil {
+		return err
+	}
+	r.pos++
+
+	var rawKey []byte
+	for {
+		if r.pos >= len(r.data) {
+			return r.syntaxError(r.pos, "'e'", "eol reached before end of dict")
+		}
+
+		if r.data[r.pos] == 'e' {
+			break
+		}
+
+		var err error
+		rawKey, err = r.readKey(rawKey) // decode map key (must be a string)
+		if err != nil {
+			return err
+		}
+		r.path = append(r.path, pathElem{key: rawKey})
+
+		// Decode map value
+		if v.Kind() == reflect.Map {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			err = r.unmarshal(elem)
+			if err != nil {
+				return err
+			}
+			v.SetMapIndex(reflect.ValueOf(string(rawKey)).Convert(v.Type().Key()), elem)
+			r.path = r.path[:len(r.path)-1]
+			continue
+		}
+
+		// Decode struct field, skipping values of undeclared keys without decoding them
+		i, ok := fields.byName[string(rawKey)]
+		if !ok {
+			_, err = r.skipElement()
+		} else {
+			seen[i] = true
+			err = r.unmarshal(fieldByIndex(v, fields.list[i].index))
+		}
+		if err != nil {
+			return err
+		}
+		r.path = r.path[:len(r.path)-1]
+	}
+
+	// Check required fields
+	for i, f := range fields.list {
+		if f.required && !seen[i] {
+			return &MissingKeyError{Key: f.name, Type: v.Type(), Offset: r.base + int64(start), Path: formatPath(r.path)}
+		}
+	}
+
+	r.pos++
+	r.depth--
+	return nil
+}
+
+// unmarshalOrderedDict decodes a bencoded dictionary into v, a Dict, decoding all the dictionaries it holds as Dict.
+func (r *bReader) unmarshalOrderedDict(v reflect.Value) error {
+	start := r.pos
+	if r.data[r.pos] != 'd' {
+		_, err := r.decodeElement() // report syntax errors first
+		if err != nil {
+			return err
+		}
+		return r.typeError(start, "non-dictionary value", v.Type())
+	}
+
+	useDict := r.useDict
+	r.useDict = true
+	val, err := r.decodeElement()
+	r.useDict = useDict
+	if err != nil {
+		return err
+	}
+	v.Set(reflect.ValueOf(val))
+	return nil
+}
+
+// Valid reports whether data is a single bencoded value in canonical form, returning a *NonCanonicalError with the
+// offset of the first violation, or another error if data is not valid bencode at all.
+func Valid(data []byte) error {
+	r := newReader(data)
+	r.strict = true
+	_, err := r.decodeElement()
+	if err != nil {
+		return fmt.Errorf("invalid bencode: %w", err)
+	}
+	if r.pos != len(data) {
+		msg := fmt.Sprintf("%d bytes of trailing data", len(data)-r.pos)
+		return fmt.Errorf("invalid bencode: %w", r.syntaxError(r.pos, "end of input", msg))
+	}
+	return nil
+}
+
+// IsCanonical reports whether data is a single bencoded value in canonical form, whose re-encoding yields the same
+// bytes.
+func IsCanonical(data []byte) bool {
+	return Valid(data) == nil
+}
@@ -1,9 +1,11 @@
 package bencode
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/brianvoe/gofakeit/v7"
@@ -65,7 +67,7 @@ func genNestedData(typeCode int, level int) (testData[interface{}], bool) {
 		if isListInvalid(list) {
 			invalid = true
 		}
-	case Dict:
+	case Dictionary:
 		dict := genDictDecodeTest(level)
 		test.data = dict.data
 		test.bCode = dict.bCode
@@ -338,3 +340,125 @@ func TestDecodeDict(t *testing.T) {
 		}
 	}
 }
+
+func TestValid(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		bCode     string
+		canonical bool
+		offset    int
+	}{
+		{"d1:ai1e1:bli-1e0:ee", true, 0},
+		{"d1:bi1e1:ai2ee", false, 7},
+		{"d1:ai1e1:ai2ee", false, 7},
+		{"li-0ee", false, 1},
+		{"li-01ee", false, -1},
+		{"d1:a03:abce", false, 4},
+		{"l1:a", false, -1},
+		{"i1ei2e", false, -1},
+	}
+
+	for _, test := range tests {
+		err := Valid([]byte(test.bCode))
+		assert.Equal(t, test.canonical, IsCanonical([]byte(test.bCode)), test.bCode)
+		if test.canonical {
+			assert.NoError(t, err, test.bCode)
+			continue
+		}
+
+		// Non-canonical values are still accepted by the default decoder
+		var nonCanonical *NonCanonicalError
+		if test.offset < 0 {
+			assert.False(t, errors.As(err, &nonCanonical), test.bCode)
+		} else if assert.ErrorAs(t, err, &nonCanonical, test.bCode) {
+			assert.Equal(t, int64(test.offset), nonCanonical.Offset, test.bCode)
+			_, err = Decode([]byte(test.bCode))
+			assert.NoError(t, err, test.bCode)
+		}
+	}
+}
+
+func TestDecoderDisallowNonCanonical(t *testing.T) {
+	t.Parallel()
+	var decoded struct {
+		A int `bencode:"a"`
+	}
+	dec := NewDecoder(strings.NewReader("d1:ai1e1:ai2ee"))
+	dec.DisallowNonCanonical()
+	var nonCanonical *NonCanonicalError
+	assert.ErrorAs(t, dec.Decode(&decoded), &nonCanonical)
+}
+
+func TestLimits(t *testing.T) {
+	t.Parallel()
+	deep := strings.Repeat("l", 600) + strings.Repeat("e", 600)
+	tests := []struct {
+		bCode  string
+		limits Limits
+		err    error
+	}{
+		{deep, DefaultLimits, ErrMaxDepth},
+		{"lli1eee", Limits{MaxDepth: 1}, ErrMaxDepth},
+		{"d3:key5:valuee", Limits{MaxStringLength: 4}, ErrMaxStringLength},
+		{"li1ei2ei3ee", Limits{MaxElements: 3}, ErrMaxElements},
+		{"li1ei2ei3ee", Limits{MaxInputSize: 10}, ErrMaxInputSize},
+		{"2000000000:partial", Limits{MaxStringLength: 1 << 20}, ErrMaxStringLength},
+		{"li1ei2ei3ee", Limits{MaxDepth: 1, MaxStringLength: 1, MaxElements: 4, MaxInputSize: 11}, nil},
+	}
+
+	for _, test := range tests {
+		var decoded interface{}
+		dec := NewDecoder(strings.NewReader(test.bCode))
+		dec.SetLimits(test.limits)
+		err := dec.Decode(&decoded)
+		if test.err == nil {
+			assert.NoError(t, err, test.bCode)
+			continue
+		}
+
+		var limitErr *LimitError
+		assert.ErrorIs(t, err, test.err, test.bCode)
+		assert.ErrorAs(t, err, &limitErr, test.bCode)
+	}
+
+	// Default limits also apply to Decode and Unmarshal
+	var decoded []interface{}
+	_, err := Decode([]byte(deep))
+	assert.ErrorIs(t, err, ErrMaxDepth)
+	assert.ErrorIs(t, Unmarshal([]byte(deep), &decoded), ErrMaxDepth)
+}
+
+func TestLimitsSkippedValues(t *testing.T) {
+	t.Parallel()
+	type partial struct {
+		Name string `bencode:"name"`
+	}
+	type nested struct {
+		Info partial `bencode:"info"`
+	}
+	long := "d5:extral" + strings.Repeat("i1e", 1000) + "e4:name1:ae"
+	tests := []struct {
+		bCode  string
+		limits Limits
+		v      interface{}
+		err    error
+	}{
+		{long, Limits{MaxElements: 10}, &partial{}, ErrMaxElements},
+		{long, Limits{MaxElements: 10}, &RawMessage{}, ErrMaxElements},
+		{long, Limits{MaxElements: 1003}, &partial{}, nil},
+		{"d4:infod5:extralleeee", Limits{MaxDepth: 3}, &nested{}, ErrMaxDepth},
+		{"d4:infod5:extrale4:name1:aee", Limits{MaxDepth: 3, MaxElements: 5}, &nested{}, nil},
+		{"d3:keyd1:ai1e1:bi2eee", Limits{MaxElements: 4}, &RawMessage{}, nil},
+	}
+
+	for _, test := range tests {
+		dec := NewDecoder(strings.NewReader(test.bCode))
+		dec.SetLimits(test.limits)
+		err := dec.Decode(test.v)
+		if test.err == nil {
+			assert.NoError(t, err, test.bCode)
+		} else {
+			assert.ErrorIs(t, err, test.err, test.bCode)
+		}
+	}
+}
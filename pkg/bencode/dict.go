@@ -0,0 +1,56 @@
+package bencode
+
+import "reflect"
+
+// DictEntry is a key/value pair of a Dict.
+type DictEntry struct {
+	// Key is the dictionary key.
+	Key string
+	// Value is the decoded value, or any value supported by Encode.
+	Value interface{}
+}
+
+// Dict is a bencode dictionary that keeps its entries in order, including duplicate keys, so a decoded document can
+// be re-encoded byte for byte, even if it is not canonical: integers and strings that are not in canonical form, such
+// as "i-0e" or "03:abc", and integers beyond 64 bits are decoded as a RawMessage of their original bytes. Only keys
+// are normalized, as strings.
+// Encode emits its entries verbatim, in order. Dicts are produced when unmarshalling into a Dict, for it and all the
+// dictionaries it holds, and by Decoders set with UseDict.
+type Dict []DictEntry
+
+// dictType is the reflection type of Dict, which is encoded in the order of its entries.
+var dictType = reflect.TypeOf(Dict{})
+
+// Get returns the value of the first entry with the given key, and whether it was found.
+func (d Dict) Get(key string) (interface{}, bool) {
+	for _, entry := range d {
+		if entry.Key == key {
+			return entry.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Set sets the value of the first entry with the given key, keeping its position, or appends a new entry if there
+// is none.
+func (d *Dict) Set(key string, value interface{}) {
+	for i := range *d {
+		if (*d)[i].Key == key {
+			(*d)[i].Value = value
+			return
+		}
+	}
+	*d = append(*d, DictEntry{Key: key, Value: value})
+}
+
+// Delete removes all the entries with the given key.
+func (d *Dict) Delete(key string) {
+	entries := (*d)[:0]
+	for _, entry := range *d {
+		if entry.Key != key {
+			entries = append(entries, entry)
+		}
+	}
+	clear((*d)[len(entries):])
+	*d = entries
+}
@@ -0,0 +1,84 @@
+package bencode
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type TestDictData struct {
+	Info Dict   `bencode:"info"`
+	Name string `bencode:"name"`
+}
+
+func TestDict(t *testing.T) {
+	t.Parallel()
+	bCode := "d1:bi1e1:ad1:zi1e1:yi2ee1:bli3ed1:d0:1:c0:eee"
+
+	var dict Dict
+	if assert.NoError(t, Unmarshal([]byte(bCode), &dict)) {
+		assert.Equal(t, Dict{
+			{Key: "b", Value: 1},
+			{Key: "a", Value: Dict{{Key: "z", Value: 1}, {Key: "y", Value: 2}}},
+			{Key: "b", Value: []interface{}{3, Dict{{Key: "d", Value: ""}, {Key: "c", Value: ""}}}},
+		}, dict)
+
+		encoded, err := Marshal(dict)
+		assert.NoError(t, err)
+		assert.Equal(t, bCode, string(encoded))
+	}
+
+	dec := NewDecoder(bytes.NewReader([]byte(bCode)))
+	dec.UseDict()
+	var decoded interface{}
+	if assert.NoError(t, dec.Decode(&decoded)) {
+		assert.Equal(t, dict, decoded)
+	}
+
+	var typeErr *UnmarshalTypeError
+	assert.ErrorAs(t, Unmarshal([]byte("li1ee"), &dict), &typeErr)
+}
+
+func TestDictNonCanonical(t *testing.T) {
+	t.Parallel()
+	bCode := "d1:bi-0e1:a03:abc1:cl002:xyi1ee1:dd1:z00:e1:ei99999999999999999999999ee"
+
+	var dict Dict
+	if assert.NoError(t, Unmarshal([]byte(bCode), &dict)) {
+		assert.Equal(t, Dict{
+			{Key: "b", Value: RawMessage("i-0e")},
+			{Key: "a", Value: RawMessage("03:abc")},
+			{Key: "c", Value: []interface{}{RawMessage("002:xy"), 1}},
+			{Key: "d", Value: Dict{{Key: "z", Value: RawMessage("00:")}}},
+			{Key: "e", Value: RawMessage("i99999999999999999999999e")},
+		}, dict)
+
+		encoded, err := Marshal(dict)
+		assert.NoError(t, err)
+		assert.Equal(t, bCode, string(encoded))
+	}
+}
+
+func TestDictField(t *testing.T) {
+	t.Parallel()
+	bCode := "d4:infod6:pieces0:4:name1:ae4:name4:teste"
+
+	var data TestDictData
+	if assert.NoError(t, Unmarshal([]byte(bCode), &data)) {
+		value, ok := data.Info.Get("name")
+		assert.True(t, ok)
+		assert.Equal(t, "a", value)
+
+		encoded, err := Marshal(&data)
+		assert.NoError(t, err)
+		assert.Equal(t, bCode, string(encoded))
+	}
+
+	data.Info.Set("name", "b")
+	data.Info.Set("length", 1)
+	data.Info.Delete("pieces")
+	assert.Equal(t, Dict{{Key: "name", Value: "b"}, {Key: "length", Value: 1}}, data.Info)
+	_, ok := data.Info.Get("pieces")
+	assert.False(t, ok)
+}
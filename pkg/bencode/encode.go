@@ -2,145 +2,252 @@ package bencode
 
 import (
 	"fmt"
+	"math/big"
 	"reflect"
-	"sort"
+	"slices"
 	"strconv"
-	"sync"
+	"strings"
+	"time"
 )
 
-// encodeString encodes a string into a byte slice as bencode string.
-func encodeString(v interface{}) ([]byte, error) {
-	s, ok := v.(string)
-	if !ok {
-		return nil, fmt.Errorf("cannot encode string: expected string, got %s", reflect.TypeOf(v))
-	}
-	strLen := len(s)
-	strLenStr := strconv.Itoa(strLen)
-
-	// Encoding
-	encoded := make([]byte, len(strLenStr)+1+strLen)
-	n := copy(encoded[:], strLenStr)
-	n += copy(encoded[n:], ":")
-	n += copy(encoded[n:], s)
-	return encoded, nil
+// bWriter is a struct for encoding values into a single growing bencode buffer.
+type bWriter struct {
+	// buf holds the encoded data.
+	buf []byte
+}
+
+// mapEntry holds a map key and value, to be encoded in sorted key order.
+type mapEntry struct {
+	// key holds the map key as a string.
+	key string
+	// val holds the map value.
+	val reflect.Value
+}
+
+// isNil reports whether v holds no value: an invalid value, or a nil pointer or interface.
+func isNil(v reflect.Value) bool {
+	return !v.IsValid() || ((v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil())
 }
 
-// encodeInt encodes an integer into a byte slice as bencode integer.
-func encodeInt(v interface{}) ([]byte, error) {
-	i, ok := v.(int)
-	if !ok {
-		return nil, fmt.Errorf("cannot encode int: expected int, got %s", reflect.TypeOf(v))
+// writeString appends a bencode string.
+func (w *bWriter) writeString(s string) {
+	w.buf = strconv.AppendInt(w.buf, int64(len(s)), 10)
+	w.buf = append(w.buf, ':')
+	w.buf = append(w.buf, s...)
+}
+
+// writeBytes appends a byte slice as bencode string.
+func (w *bWriter) writeBytes(b []byte) {
+	w.buf = strconv.AppendInt(w.buf, int64(len(b)), 10)
+	w.buf = append(w.buf, ':')
+	w.buf = append(w.buf, b...)
+}
+
+// writeMarshaler appends the output of a Marshaler, checking that it is a single valid bencoded value.
+func (w *bWriter) writeMarshaler(m Marshaler) error {
+	encoded, err := m.MarshalBencode()
+	if err != nil {
+		return fmt.Errorf("cannot encode %s: %w", reflect.TypeOf(m), err)
 	}
-	intStr := strconv.Itoa(i)
-
-	// Encoding
-	encoded := make([]byte, len(intStr)+2)
-	n := copy(encoded[:], "i")
-	n += copy(encoded[n:], intStr)
-	n += copy(encoded[n:], "e")
-	return encoded, nil
+
+	scan := scanState{}
+	end, err := scan.scan(encoded)
+	if err != nil || end != len(encoded) {
+		return fmt.Errorf("cannot encode %s: MarshalBencode returned invalid bencode", reflect.TypeOf(m))
+	}
+	w.buf = append(w.buf, encoded...)
+	return nil
 }
 
-// encodeList encodes an array into a byte slice as bencode list.
-func encodeList(v interface{}) ([]byte, error) {
-	// Type check and create []interface
-	if reflect.ValueOf(v).Kind() != reflect.Slice || reflect.ValueOf(v).Kind() == reflect.Array {
-		return nil, fmt.Errorf("cannot encode list: expected slice or array, got %s", reflect.TypeOf(v))
+// encode appends the bencode encoding of v.
+func (w *bWriter) encode(v reflect.Value) error {
+	if isNil(v) {
+		return fmt.Errorf("cannot encode nil value")
 	}
-	s := make([]interface{}, reflect.ValueOf(v).Len())
-	for i := 0; i < reflect.ValueOf(v).Len(); i++ {
-		s[i] = reflect.ValueOf(v).Index(i).Interface()
+	if m, ok := marshaler(v); ok { // encoded by its own method
+		return w.writeMarshaler(m)
 	}
 
-	// Buffer and encoding each values concurrently
-	elem := make([][]byte, len(s))
-	totalBytes := 2
-	for i, val := range s {
-		var err error
-		elem[i], err = Encode(val)
-		if err != nil {
-			return nil, fmt.Errorf("cannot encode list: %w", err)
+	switch {
+	case v.Type() == dictType: // encoded verbatim
+		return w.encodeDict(v.Interface().(Dict))
+	case v.Type() == timeType: // encoded as Unix seconds
+		w.buf = append(w.buf, 'i')
+		w.buf = strconv.AppendInt(w.buf, v.Interface().(time.Time).Unix(), 10)
+		w.buf = append(w.buf, 'e')
+		return nil
+	case v.Type() == bigIntType: // encoded as integer
+		var n *big.Int
+		if v.CanAddr() {
+			n = v.Addr().Interface().(*big.Int)
+		} else {
+			x := v.Interface().(big.Int)
+			n = &x
 		}
-		totalBytes += len(elem[i])
+		w.buf = append(w.buf, 'i')
+		w.buf = n.Append(w.buf, 10)
+		w.buf = append(w.buf, 'e')
+		return nil
 	}
 
-	// Encoding the list
-	encoded := make([]byte, totalBytes)
-	i := copy(encoded[:], "l")
-	for _, val := range elem {
-		i += copy(encoded[i:], val)
+	switch v.Kind() {
+	case reflect.String:
+		w.writeString(v.String())
+	case reflect.Bool:
+		if v.Bool() {
+			w.buf = append(w.buf, "i1e"...)
+		} else {
+			w.buf = append(w.buf, "i0e"...)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		w.buf = append(w.buf, 'i')
+		w.buf = strconv.AppendInt(w.buf, v.Int(), 10)
+		w.buf = append(w.buf, 'e')
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		w.buf = append(w.buf, 'i')
+		w.buf = strconv.AppendUint(w.buf, v.Uint(), 10)
+		w.buf = append(w.buf, 'e')
+	case reflect.Slice, reflect.Array:
+		return w.encodeList(v)
+	case reflect.Map:
+		return w.encodeMap(v)
+	case reflect.Struct:
+		return w.encodeStruct(v)
+	case reflect.Ptr, reflect.Interface:
+		return w.encode(v.Elem())
+	default:
+		return fmt.Errorf("cannot encode type %s", v.Type())
 	}
-	i += copy(encoded[i:], "e")
-	return encoded, nil
+	return nil
 }
 
-// encodeLMap encodes a map into a byte slice as bencode dictionary.
-func encodeMap(v interface{}) ([]byte, error) {
-	var wg sync.WaitGroup
+// encodeList appends a slice or array as bencode list, or as bencode string if its elements are bytes.
+func (w *bWriter) encodeList(v reflect.Value) error {
+	if v.Type().Elem().Kind() == reflect.Uint8 {
+		if v.Kind() == reflect.Slice {
+			w.writeBytes(v.Bytes())
+		} else {
+			w.buf = strconv.AppendInt(w.buf, int64(v.Len()), 10)
+			w.buf = append(w.buf, ':')
+			for i := range v.Len() {
+				w.buf = append(w.buf, byte(v.Index(i).Uint()))
+			}
+		}
+		return nil
+	}
 
-	// Type check and create keys and values array
-	if reflect.ValueOf(v).Kind() != reflect.Map {
-		return nil, fmt.Errorf("cannot encode map: expected map, got %s", reflect.TypeOf(v))
+	w.buf = append(w.buf, 'l')
+	for i := range v.Len() {
+		err := w.encode(v.Index(i))
+		if err != nil {
+			return fmt.Errorf("cannot encode list: %w", err)
+		}
 	}
+	w.buf = append(w.buf, 'e')
+	return nil
+}
 
-	sortedKeys := make([]string, reflect.ValueOf(v).Len())
-	m := make(map[string]interface{}, reflect.ValueOf(v).Len())
-	for i, k := range reflect.ValueOf(v).MapKeys() {
-		sortedKeys[i] = k.String()
-		m[k.String()] = reflect.ValueOf(v).MapIndex(k).Interface()
+// encodeMap appends a map with string keys as bencode dictionary, sorted by key. Entries holding nil values are
+// omitted.
+func (w *bWriter) encodeMap(v reflect.Value) error {
+	if v.Type().Key().Kind() != reflect.String {
+		return fmt.Errorf("cannot encode map: expected string keys, got %s", v.Type().Key())
 	}
 
-	// Concurrently sort array
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		sort.Strings(sortedKeys)
-	}()
-
-	// Buffer and encoding each key-pair values concurrently
-	totalBytes := 2
-	for key, val := range m {
-		keyStr, err := encodeString(key)
+	// Copy keys and values into preallocated storage, instead of allocating them one by one
+	key := reflect.New(v.Type().Key()).Elem()
+	values := reflect.MakeSlice(reflect.SliceOf(v.Type().Elem()), v.Len(), v.Len())
+	entries := make([]mapEntry, 0, v.Len())
+	for iter := v.MapRange(); iter.Next(); {
+		val := values.Index(len(entries))
+		key.SetIterKey(iter)
+		val.SetIterValue(iter)
+		if !isNil(val) {
+			entries = append(entries, mapEntry{key: key.String(), val: val})
+		}
+	}
+	slices.SortFunc(entries, func(a, b mapEntry) int {
+		return strings.Compare(a.key, b.key)
+	})
+
+	w.buf = append(w.buf, 'd')
+	for _, entry := range entries {
+		w.writeString(entry.key)
+		err := w.encode(entry.val)
 		if err != nil {
-			return nil, fmt.Errorf("cannot encode map: %w", err)
+			return fmt.Errorf("cannot encode map: %w", err)
 		}
+	}
+	w.buf = append(w.buf, 'e')
+	return nil
+}
 
-		valStr, err := Encode(val)
+// encodeDict appends a Dict as bencode dictionary, with its entries in their order, including duplicate keys.
+func (w *bWriter) encodeDict(d Dict) error {
+	w.buf = append(w.buf, 'd')
+	for _, entry := range d {
+		w.writeString(entry.Key)
+		err := w.encode(reflect.ValueOf(entry.Value))
 		if err != nil {
-			return nil, fmt.Errorf("cannot encode map: %w", err)
+			return fmt.Errorf("cannot encode dict entry '%s': %w", entry.Key, err)
 		}
-
-		m[key] = append(keyStr, valStr...)
-		totalBytes += len(keyStr) + len(valStr)
 	}
+	w.buf = append(w.buf, 'e')
+	return nil
+}
 
-	// Encoding the map
-	wg.Wait()
-	encoded := make([]byte, totalBytes)
-	i := copy(encoded[:], "d")
-	for _, key := range sortedKeys {
-		i += copy(encoded[i:], m[key].([]byte))
+// encodeStruct appends a struct as bencode dictionary, using its fields "bencode" tags as keys, honouring the
+// "omitempty" and "inline" options. Fields holding nil values are omitted.
+func (w *bWriter) encodeStruct(v reflect.Value) error {
+	fields := cachedFields(v.Type())
+
+	w.buf = append(w.buf, 'd')
+next:
+	for _, i := range fields.sorted {
+		f := fields.list[i]
+
+		// Get field, skipping it if it is inside a nil inline struct pointer
+		structField := v
+		for j, x := range f.index {
+			if j > 0 && structField.Kind() == reflect.Ptr {
+				if structField.IsNil() {
+					continue next
+				}
+				structField = structField.Elem()
+			}
+			structField = structField.Field(x)
+		}
+		if isNil(structField) || (f.omitEmpty && isEmptyValue(structField)) {
+			continue
+		}
+
+		w.writeString(f.name)
+		err := w.encode(structField)
+		if err != nil {
+			return fmt.Errorf("cannot encode struct field '%s': %w", f.name, err)
+		}
 	}
-	i += copy(encoded[i:], "e")
-	return encoded, nil
+	w.buf = append(w.buf, 'e')
+	return nil
 }
 
-// Encode encodes a value into a byte slice using bencode format. Supported types are string, int, slice, and map.
+// Encode encodes a value into a byte slice using bencode format. Supported types are strings, byte slices and
+// arrays, integers of any kind, big.Int, bool, time.Time (as Unix seconds), slices, arrays, maps with string keys,
+// structs (see Marshal), pointers to these and types implementing Marshaler.
 func Encode(v interface{}) ([]byte, error) {
-	if v == nil {
-		return nil, nil
+	w := &bWriter{}
+	err := w.encodeValue(v)
+	if err != nil {
+		return nil, err
 	}
+	return w.buf, nil
+}
 
-	switch reflect.TypeOf(v).Kind() {
-	case reflect.String:
-		return encodeString(v)
-	case reflect.Int:
-		return encodeInt(v)
-	case reflect.Slice:
-		return encodeList(v)
-	case reflect.Map:
-		return encodeMap(v)
-	default:
-		return nil, fmt.Errorf("cannot encode type %s", reflect.TypeOf(v))
+// encodeValue resets w and appends the bencode encoding of v to its buffer, reusing its capacity.
+func (w *bWriter) encodeValue(v any) error {
+	w.buf = w.buf[:0]
+	if v == nil {
+		return nil
 	}
+	return w.encode(reflect.ValueOf(v))
 }
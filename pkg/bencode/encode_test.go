@@ -3,8 +3,10 @@ package bencode
 import (
 	"github.com/brianvoe/gofakeit/v7"
 	"github.com/stretchr/testify/assert"
+	"math/big"
 	"sort"
 	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -20,7 +22,7 @@ func switchGenType(typeCode int, level int) testData[interface{}] {
 	case List:
 		val := genListEncodeTest(level)
 		test.data, test.bCode = val.data, val.bCode
-	case Dict:
+	case Dictionary:
 		val := genDictEncodeTest(level)
 		test.data, test.bCode = val.data, val.bCode
 	}
@@ -170,7 +172,7 @@ func TestEncodeDict(t *testing.T) {
 			continue
 		}
 
-		mock := switchGenType(Dict, 0)
+		mock := switchGenType(Dictionary, 0)
 		test, expected := mock.data.(map[string]interface{}), mock.bCode
 		bCode, err := Encode(test)
 		if assert.NoError(t, err) {
@@ -178,3 +180,130 @@ func TestEncodeDict(t *testing.T) {
 		}
 	}
 }
+
+type TestIntegerKindsData struct {
+	Int8    int8     `bencode:"int8"`
+	Int16   int16    `bencode:"int16"`
+	Int32   int32    `bencode:"int32"`
+	Int64   int64    `bencode:"int64"`
+	Uint    uint     `bencode:"uint"`
+	Uint8   uint8    `bencode:"uint8"`
+	Uint16  uint16   `bencode:"uint16"`
+	Uint32  uint32   `bencode:"uint32"`
+	Uint64  uint64   `bencode:"uint64"`
+	Big     big.Int  `bencode:"big"`
+	BigPtr  *big.Int `bencode:"big ptr"`
+	Missing *big.Int `bencode:"missing,omitempty"`
+}
+
+func TestEncodeIntegerKinds(t *testing.T) {
+	t.Parallel()
+	for range *SimNumbers {
+		seed := gofakeit.Int64()
+		if gofakeit.Seed(seed) != nil {
+			continue
+		}
+
+		// Data, with big integers beyond 64 bits
+		big1, _ := new(big.Int).SetString(strconv.FormatUint(gofakeit.Uint64(), 10)+"0000000000", 10)
+		big2 := new(big.Int).Neg(big1)
+		testMarshal := TestIntegerKindsData{
+			Int8: gofakeit.Int8(), Int16: gofakeit.Int16(), Int32: gofakeit.Int32(), Int64: gofakeit.Int64(),
+			Uint: gofakeit.Uint(), Uint8: gofakeit.Uint8(), Uint16: gofakeit.Uint16(), Uint32: gofakeit.Uint32(),
+			Uint64: gofakeit.Uint64(), Big: *big1, BigPtr: big2,
+		}
+		expectedBCode := "d3:bigi" + big1.String() + "e" +
+			"7:big ptri" + big2.String() + "e" +
+			"5:int16i" + strconv.Itoa(int(testMarshal.Int16)) + "e" +
+			"5:int32i" + strconv.Itoa(int(testMarshal.Int32)) + "e" +
+			"5:int64i" + strconv.FormatInt(testMarshal.Int64, 10) + "e" +
+			"4:int8i" + strconv.Itoa(int(testMarshal.Int8)) + "e" +
+			"4:uinti" + strconv.FormatUint(uint64(testMarshal.Uint), 10) + "e" +
+			"6:uint16i" + strconv.Itoa(int(testMarshal.Uint16)) + "e" +
+			"6:uint32i" + strconv.FormatUint(uint64(testMarshal.Uint32), 10) + "e" +
+			"6:uint64i" + strconv.FormatUint(testMarshal.Uint64, 10) + "e" +
+			"5:uint8i" + strconv.Itoa(int(testMarshal.Uint8)) + "e" + "e"
+
+		// Tests
+		bCode, err := Marshal(&testMarshal)
+		if assert.NoError(t, err, FormatSeed(seed)) && assert.Equal(t, expectedBCode, string(bCode), FormatSeed(seed)) {
+			testUnmarshal := TestIntegerKindsData{}
+			if assert.NoError(t, Unmarshal(bCode, &testUnmarshal), FormatInfo(seed, string(bCode))) {
+				assert.Equal(t, 0, testMarshal.Big.Cmp(&testUnmarshal.Big), FormatInfo(seed, string(bCode)))
+				assert.Equal(t, 0, testMarshal.BigPtr.Cmp(testUnmarshal.BigPtr), FormatInfo(seed, string(bCode)))
+				testMarshal.Big, testMarshal.BigPtr = big.Int{}, nil
+				testUnmarshal.Big, testUnmarshal.BigPtr = big.Int{}, nil
+				assert.Equal(t, testMarshal, testUnmarshal, FormatInfo(seed, string(bCode)))
+			}
+		}
+	}
+}
+
+func TestDecodeBigInt(t *testing.T) {
+	t.Parallel()
+	bCode := "li18446744073709551616ei-9223372036854775808ee"
+
+	// Oversized integers fail unless big integers are enabled
+	var decoded interface{}
+	var typeErr *UnmarshalTypeError
+	_, err := Decode([]byte(bCode))
+	assert.ErrorAs(t, err, &typeErr)
+
+	dec := NewDecoder(strings.NewReader(bCode))
+	dec.UseBigInt()
+	if assert.NoError(t, dec.Decode(&decoded)) {
+		expected, _ := new(big.Int).SetString("18446744073709551616", 10)
+		assert.Equal(t, expected, decoded.([]interface{})[0])
+		assert.EqualValues(t, int64(-9223372036854775808), decoded.([]interface{})[1])
+	}
+}
+
+// benchmarkTorrent mimics a single-file torrent with a large pieces string, for encoding benchmarks.
+type benchmarkTorrent struct {
+	Announce string `bencode:"announce"`
+	Comment  string `bencode:"comment"`
+	Info     struct {
+		Length      int    `bencode:"length"`
+		Name        string `bencode:"name"`
+		PieceLength int    `bencode:"piece length"`
+		Pieces      string `bencode:"pieces"`
+	} `bencode:"info"`
+}
+
+func BenchmarkEncodePieces(b *testing.B) {
+	torrent := benchmarkTorrent{Announce: "http://tracker.example.com/announce", Comment: "benchmark"}
+	torrent.Info.Length = 4 << 30
+	torrent.Info.Name = "benchmark.iso"
+	torrent.Info.PieceLength = 256 << 10
+	torrent.Info.Pieces = strings.Repeat("01234567890123456789", torrent.Info.Length/torrent.Info.PieceLength)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		if _, err := Marshal(&torrent); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncodeDict(b *testing.B) {
+	// Resume-like dictionary with many nested entries
+	session := make(map[string]interface{}, 1000)
+	for i := range 1000 {
+		session["torrent-"+strconv.Itoa(i)] = map[string]interface{}{
+			"info-hash":  strings.Repeat("h", 20),
+			"downloaded": i * 1024,
+			"uploaded":   i * 512,
+			"save path":  "/downloads/" + strconv.Itoa(i),
+			"trackers":   []interface{}{"http://a.example.com/announce", "udp://b.example.com:80"},
+		}
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		if _, err := Encode(session); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package bencode
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// SyntaxError describes malformed bencode found while decoding.
+type SyntaxError struct {
+	// Offset is the position in the input where the error was found.
+	Offset int64
+	// Expected describes the token expected at Offset, e.g. "'e'" or "string length", if known.
+	Expected string
+	// Path is the location of the malformed value in the document, e.g. "info.files[3].length".
+	Path string
+	// msg describes the error.
+	msg string
+}
+
+// Error returns a description of the syntax error, its offset and path.
+func (e *SyntaxError) Error() string {
+	s := "syntax error at offset " + strconv.FormatInt(e.Offset, 10)
+	if e.Path != "" {
+		s += " (" + e.Path + ")"
+	}
+	s += ": " + e.msg
+	if e.Expected != "" {
+		s += ", expected " + e.Expected
+	}
+	return s
+}
+
+// UnmarshalTypeError describes a bencode value that cannot be stored in a Go value of a specific type.
+type UnmarshalTypeError struct {
+	// Value describes the bencode value, e.g. "integer 300" or "list".
+	Value string
+	// Type is the type of the Go value it could not be assigned to.
+	Type reflect.Type
+	// Offset is the position in the input where the value starts.
+	Offset int64
+	// Path is the location of the value in the document, e.g. "info.files[3].length".
+	Path string
+}
+
+// Error returns a description of the type mismatch, its offset and path.
+func (e *UnmarshalTypeError) Error() string {
+	s := "cannot unmarshal bencode " + e.Value + " into Go value of type " + e.Type.String()
+	if e.Path != "" {
+		s += " (" + e.Path + ")"
+	}
+	return s + " at offset " + strconv.FormatInt(e.Offset, 10)
+}
+
+// MissingKeyError describes a dictionary lacking a key required by the struct it is decoded into.
+type MissingKeyError struct {
+	// Key is the missing dictionary key.
+	Key string
+	// Type is the type of the struct requiring the key.
+	Type reflect.Type
+	// Offset is the position in the input where the dictionary starts.
+	Offset int64
+	// Path is the location of the dictionary in the document, e.g. "info.files[3]".
+	Path string
+}
+
+// Error returns a description of the missing key, its offset and path.
+func (e *MissingKeyError) Error() string {
+	s := "missing required key '" + e.Key + "' for Go value of type " + e.Type.String()
+	if e.Path != "" {
+		s += " (" + e.Path + ")"
+	}
+	return s + " at offset " + strconv.FormatInt(e.Offset, 10)
+}
+
+// NonCanonicalError describes a bencoded value that is valid, but not in its canonical form, found at Offset.
+type NonCanonicalError struct {
+	// Offset is the position in the input where the violation was found.
+	Offset int64
+	// Path is the location of the value in the document, e.g. "info.files[3].length".
+	Path string
+	// Reason describes the violation.
+	Reason string
+}
+
+// Error returns a description of the non-canonical value and its offset.
+func (e *NonCanonicalError) Error() string {
+	if e.Path != "" {
+		return fmt.Sprintf("non-canonical bencode at offset %d (%s): %s", e.Offset, e.Path, e.Reason)
+	}
+	return fmt.Sprintf("non-canonical bencode at offset %d: %s", e.Offset, e.Reason)
+}
+
+// Errors wrapped by a *LimitError, identifying the exceeded limit.
+var (
+	// ErrMaxDepth is reported when lists and dictionaries are nested deeper than Limits.MaxDepth.
+	ErrMaxDepth = errors.New("maximum nesting depth exceeded")
+	// ErrMaxStringLength is reported when a string is longer than Limits.MaxStringLength.
+	ErrMaxStringLength = errors.New("maximum string length exceeded")
+	// ErrMaxElements is reported when a document holds more values than Limits.MaxElements.
+	ErrMaxElements = errors.New("maximum number of elements exceeded")
+	// ErrMaxInputSize is reported when a document is larger than Limits.MaxInputSize.
+	ErrMaxInputSize = errors.New("maximum input size exceeded")
+)
+
+// ErrNotFound is reported by Get and the other path queries when the path does not exist in the document.
+var ErrNotFound = errors.New("path not found")
+
+// LimitError describes input exceeding one of the decoding Limits. It wraps one of ErrMaxDepth, ErrMaxStringLength,
+// ErrMaxElements or ErrMaxInputSize, so it can be checked with errors.Is.
+type LimitError struct {
+	// Err is the error identifying the exceeded limit.
+	Err error
+	// Limit is the value of the exceeded limit.
+	Limit int64
+	// Offset is the position in the input where the limit was exceeded.
+	Offset int64
+	// Path is the location of the value in the document, e.g. "info.files[3].path".
+	Path string
+}
+
+// Error returns a description of the exceeded limit, its offset and path.
+func (e *LimitError) Error() string {
+	s := e.Err.Error() + " (limit " + strconv.FormatInt(e.Limit, 10) + ") at offset " + strconv.FormatInt(e.Offset, 10)
+	if e.Path != "" {
+		s += " (" + e.Path + ")"
+	}
+	return s
+}
+
+// Unwrap returns the error identifying the exceeded limit.
+func (e *LimitError) Unwrap() error {
+	return e.Err
+}
+
+// pathElem is a step of the path leading to the value being decoded: a dictionary key or a list index.
+type pathElem struct {
+	// key holds the dictionary key, or nil for list indexes.
+	key []byte
+	// index holds the list index, if key is nil.
+	index int
+}
+
+// formatPath formats a path as dictionary keys separated by dots, followed by list indexes in brackets, e.g.
+// "info.files[3].length".
+func formatPath(path []pathElem) string {
+	var sb strings.Builder
+	for _, elem := range path {
+		if elem.key == nil {
+			sb.WriteString("[" + strconv.Itoa(elem.index) + "]")
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteByte('.')
+		}
+		sb.Write(elem.key)
+	}
+	return sb.String()
+}
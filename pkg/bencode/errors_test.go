@@ -0,0 +1,118 @@
+package bencode
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type TestErrorsFile struct {
+	Length int64    `bencode:"length"`
+	Path   []string `bencode:"path"`
+}
+
+type TestErrorsData struct {
+	Info struct {
+		Files []TestErrorsFile `bencode:"files"`
+	} `bencode:"info"`
+}
+
+func TestSyntaxError(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		bCode    string
+		offset   int64
+		path     string
+		expected string
+	}{
+		{"d4:infod5:filesld6:lengthi1x2eeeee", 25, "info.files[0].length", "digits"},
+		{"d4:infod5:filesld4:pathl3:abcee", 31, "info.files", "'e'"},
+		{"d4:infod5:filesl", 16, "info.files", "'e'"},
+		{"d4:info", 7, "info", "value"},
+		{"di1ei2ee", 1, "", "string key"},
+		{"x", 0, "", "'i', 'l', 'd', or '0'-'9'"},
+	}
+
+	for _, test := range tests {
+		var syntaxErr *SyntaxError
+		err := Unmarshal([]byte(test.bCode), &TestErrorsData{})
+		if assert.ErrorAs(t, err, &syntaxErr, test.bCode) {
+			assert.Equal(t, test.offset, syntaxErr.Offset, test.bCode)
+			assert.Equal(t, test.path, syntaxErr.Path, test.bCode)
+			assert.Equal(t, test.expected, syntaxErr.Expected, test.bCode)
+		}
+
+		_, err = Decode([]byte(test.bCode))
+		assert.ErrorAs(t, err, &syntaxErr, test.bCode)
+	}
+}
+
+func TestUnmarshalTypeError(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		bCode  string
+		offset int64
+		path   string
+		value  string
+		typ    reflect.Type
+	}{
+		{
+			"d4:infod5:filesld6:lengthi1e4:pathl3:abcee" + "d6:length3:abceeee", 51,
+			"info.files[1].length", "string", reflect.TypeOf(int64(0)),
+		},
+		{"d4:infod5:filesd1:ai1eeee", 15, "info.files", "dictionary", reflect.TypeOf([]TestErrorsFile{})},
+		{"d4:infod5:filesld4:pathli1eeeeee", 24, "info.files[0].path[0]", "integer", reflect.TypeOf("")},
+		{
+			"d4:infod5:filesld6:lengthi9223372036854775808eeeee", 25,
+			"info.files[0].length", "integer 9223372036854775808", reflect.TypeOf(int64(0)),
+		},
+	}
+
+	for _, test := range tests {
+		var typeErr *UnmarshalTypeError
+		err := Unmarshal([]byte(test.bCode), &TestErrorsData{})
+		if assert.ErrorAs(t, err, &typeErr, test.bCode) {
+			assert.Equal(t, test.offset, typeErr.Offset, test.bCode)
+			assert.Equal(t, test.path, typeErr.Path, test.bCode)
+			assert.Equal(t, test.value, typeErr.Value, test.bCode)
+			assert.Equal(t, test.typ, typeErr.Type, test.bCode)
+		}
+	}
+}
+
+type TestMissingKeyData struct {
+	Info struct {
+		Files []struct {
+			Path []string `bencode:"path,required"`
+		} `bencode:"files"`
+	} `bencode:"info"`
+}
+
+func TestMissingKeyError(t *testing.T) {
+	t.Parallel()
+	var missingErr *MissingKeyError
+	var syntaxErr *SyntaxError
+	data := TestMissingKeyData{}
+	err := Unmarshal([]byte("d4:infod5:filesld4:pathl1:aeed6:lengthi1eeeee"), &data)
+	if assert.ErrorAs(t, err, &missingErr) {
+		assert.Equal(t, "path", missingErr.Key)
+		assert.Equal(t, reflect.TypeOf(data.Info.Files).Elem(), missingErr.Type)
+		assert.Equal(t, int64(29), missingErr.Offset)
+		assert.Equal(t, "info.files[1]", missingErr.Path)
+	}
+	assert.False(t, errors.As(err, &syntaxErr))
+}
+
+func TestDecoderSyntaxErrorOffset(t *testing.T) {
+	t.Parallel()
+	var syntaxErr *SyntaxError
+	var decoded interface{}
+	dec := NewDecoder(strings.NewReader("i1e" + "d3:keyi1e3:vali-xee"))
+	if assert.NoError(t, dec.Decode(&decoded)) && assert.ErrorAs(t, dec.Decode(&decoded), &syntaxErr) {
+		assert.Equal(t, int64(17), syntaxErr.Offset)
+		assert.Equal(t, "val", syntaxErr.Path)
+	}
+}
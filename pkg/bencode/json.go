@@ -0,0 +1,254 @@
+package bencode
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// JSONBinaryPrefix marks JSON strings holding the base64 encoding of a bencode string that is not valid UTF-8, or
+// that starts with the prefix itself, so the conversion to JSON can be reversed without losses.
+const JSONBinaryPrefix = "base64:"
+
+// dumpBinaryBytes is the number of leading bytes shown by Dump for binary strings, which are abbreviated beyond it.
+const dumpBinaryBytes = 16
+
+// appendJSONString appends s as a JSON string, escaping it with JSONBinaryPrefix if it is binary.
+func appendJSONString(buf []byte, s []byte) []byte {
+	if !utf8.Valid(s) || bytes.HasPrefix(s, []byte(JSONBinaryPrefix)) {
+		buf = append(buf, '"')
+		buf = append(buf, JSONBinaryPrefix...)
+		buf = base64.StdEncoding.AppendEncode(buf, s)
+		return append(buf, '"')
+	}
+
+	buf = append(buf, '"')
+	for _, c := range s {
+		switch {
+		case c == '"' || c == '\\':
+			buf = append(buf, '\\', c)
+		case c == '\n':
+			buf = append(buf, `\n`...)
+		case c == '\r':
+			buf = append(buf, `\r`...)
+		case c == '\t':
+			buf = append(buf, `\t`...)
+		case c < 0x20:
+			buf = fmt.Appendf(buf, `\u%04x`, c)
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return append(buf, '"')
+}
+
+// ToJSON converts a single bencoded value into JSON. Integers become JSON numbers, keeping all their digits, lists
+// become arrays and dictionaries become objects with their keys in the original order. Strings that are valid UTF-8
+// become JSON strings; other strings, such as piece hashes, are base64-encoded and marked with JSONBinaryPrefix.
+// FromJSON reverses the conversion.
+func ToJSON(data []byte) ([]byte, error) {
+	s := NewScanner(data)
+	var buf []byte
+	for {
+		tok, err := s.Next()
+		if err != nil {
+			return nil, fmt.Errorf("could not convert bencode to JSON: %w", err)
+		}
+
+		// Separate keys and values from the previous ones
+		if tok.Kind != ListEnd && tok.Kind != DictEnd && len(buf) > 0 {
+			switch buf[len(buf)-1] {
+			case '[', '{', ':':
+			default:
+				buf = append(buf, ',')
+			}
+		}
+
+		switch tok.Kind {
+		case IntToken:
+			buf = append(buf, tok.Value...)
+		case StringToken:
+			buf = appendJSONString(buf, tok.Value)
+		case KeyToken:
+			buf = appendJSONString(buf, tok.Value)
+			buf = append(buf, ':')
+		case ListStart:
+			buf = append(buf, '[')
+		case ListEnd:
+			buf = append(buf, ']')
+		case DictStart:
+			buf = append(buf, '{')
+		case DictEnd:
+			buf = append(buf, '}')
+		}
+
+		if s.Depth() == 0 {
+			break
+		}
+	}
+
+	if s.Offset() != int64(len(data)) {
+		msg := fmt.Sprintf("%d bytes of trailing data", int64(len(data))-s.Offset())
+		err := s.r.syntaxError(s.r.pos, "end of input", msg)
+		return nil, fmt.Errorf("could not convert bencode to JSON: %w", err)
+	}
+	return buf, nil
+}
+
+// FromJSON converts a JSON value produced by ToJSON back into bencode, keeping the order of object keys. JSON
+// numbers must be integers; booleans and null cannot be converted.
+func FromJSON(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	w := &bWriter{}
+	err := w.fromJSON(dec)
+	if err == nil {
+		if _, tokErr := dec.Token(); !errors.Is(tokErr, io.EOF) {
+			err = errors.New("trailing data after JSON value")
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not convert JSON to bencode: %w", err)
+	}
+	return w.buf, nil
+}
+
+// fromJSON appends the bencode encoding of the next JSON value read from dec.
+func (w *bWriter) fromJSON(dec *json.Decoder) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	switch tok := tok.(type) {
+	case json.Delim:
+		if tok == '[' {
+			w.buf = append(w.buf, 'l')
+		} else {
+			w.buf = append(w.buf, 'd')
+		}
+		for dec.More() {
+			if tok == '{' {
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				if err = w.fromJSONString(key.(string)); err != nil {
+					return err
+				}
+			}
+			if err = w.fromJSON(dec); err != nil {
+				return err
+			}
+		}
+		if _, err = dec.Token(); err != nil { // closing delimiter
+			return err
+		}
+		w.buf = append(w.buf, 'e')
+	case json.Number:
+		digits := strings.TrimPrefix(tok.String(), "-")
+		if !isDigits([]byte(digits)) {
+			return fmt.Errorf("JSON number %s is not an integer", tok)
+		}
+		w.buf = append(w.buf, 'i')
+		w.buf = append(w.buf, tok...)
+		w.buf = append(w.buf, 'e')
+	case string:
+		return w.fromJSONString(tok)
+	default:
+		return fmt.Errorf("cannot convert JSON value %v to bencode", tok)
+	}
+	return nil
+}
+
+// fromJSONString appends a JSON string as bencode string, decoding it if it is marked with JSONBinaryPrefix.
+func (w *bWriter) fromJSONString(s string) error {
+	if encoded, ok := strings.CutPrefix(s, JSONBinaryPrefix); ok {
+		b, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return fmt.Errorf("invalid binary string %q: %w", s, err)
+		}
+		w.writeBytes(b)
+		return nil
+	}
+	w.writeString(s)
+	return nil
+}
+
+// Dump formats a single bencoded value in a human-readable, indented form, for debugging and tests. Dictionary keys
+// keep their original order, text strings are quoted, and binary strings are shown in hexadecimal,
+// abbreviated to their first bytes when long, e.g. the pieces of a torrent.
+func Dump(data []byte) (string, error) {
+	s := NewScanner(data)
+	var sb strings.Builder
+	var prev TokenKind
+	for {
+		depth := s.Depth()
+		tok, err := s.Next()
+		if err != nil {
+			return "", fmt.Errorf("could not dump bencode: %w", err)
+		}
+
+		// Start each key or value on a new line, except values following their keys and empty lists and dicts
+		empty := (prev == ListStart && tok.Kind == ListEnd) || (prev == DictStart && tok.Kind == DictEnd)
+		if prev != 0 && prev != KeyToken && !empty {
+			if tok.Kind == ListEnd || tok.Kind == DictEnd {
+				depth--
+			}
+			sb.WriteString("\n")
+			sb.WriteString(strings.Repeat("  ", depth))
+		}
+		prev = tok.Kind
+
+		switch tok.Kind {
+		case IntToken:
+			sb.Write(tok.Value)
+		case StringToken:
+			sb.WriteString(dumpString(tok.Value))
+		case KeyToken:
+			sb.WriteString(strconv.Quote(string(tok.Value)) + ": ")
+		case ListStart:
+			sb.WriteString("[")
+		case ListEnd:
+			sb.WriteString("]")
+		case DictStart:
+			sb.WriteString("{")
+		case DictEnd:
+			sb.WriteString("}")
+		}
+
+		if s.Depth() == 0 {
+			break
+		}
+	}
+	return sb.String(), nil
+}
+
+// dumpString formats a string for Dump: quoted if it is text, otherwise in hexadecimal with its length, with only
+// its first bytes shown if it is long.
+func dumpString(s []byte) string {
+	if isText(s) {
+		return strconv.Quote(string(s))
+	}
+	if len(s) > dumpBinaryBytes {
+		return fmt.Sprintf("<%d bytes: %x...>", len(s), s[:dumpBinaryBytes])
+	}
+	return fmt.Sprintf("<%d bytes: %x>", len(s), s)
+}
+
+// isText reports whether s is valid UTF-8 without control characters, other than tabs and line breaks.
+func isText(s []byte) bool {
+	for _, c := range s {
+		if c < 0x20 && c != '\t' && c != '\n' && c != '\r' || c == 0x7f {
+			return false
+		}
+	}
+	return utf8.Valid(s)
+}
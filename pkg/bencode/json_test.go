@@ -0,0 +1,63 @@
+package bencode
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSON(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		bCode    string
+		expected string
+	}{
+		{"i-42e", "-42"},
+		{"i123456789012345678901234567890e", "123456789012345678901234567890"},
+		{"le", "[]"},
+		{"d1:bi1e1:ali2e0:ee", `{"b":1,"a":[2,""]}`},
+		{"d4:name7:a \"b\"\n\x01e", `{"name":"a \"b\"\n\u0001"}`},
+		{"4:\xff\x00\x01\x02", `"base64:/wABAg=="`},
+		{"9:base64:ab", `"base64:YmFzZTY0OmFi"`},
+	}
+
+	for _, test := range tests {
+		converted, err := ToJSON([]byte(test.bCode))
+		if assert.NoError(t, err, test.bCode) {
+			assert.Equal(t, test.expected, string(converted), test.bCode)
+		}
+
+		reverted, err := FromJSON(converted)
+		if assert.NoError(t, err, test.bCode) {
+			assert.Equal(t, test.bCode, string(reverted), test.bCode)
+		}
+	}
+
+	for _, invalid := range []string{"1.5", "true", "null", `"base64:!"`, "[1] 2"} {
+		_, err := FromJSON([]byte(invalid))
+		assert.Error(t, err, invalid)
+	}
+	for _, invalid := range []string{"i1ei2e", "li-05ee", "i-00e"} {
+		_, err := ToJSON([]byte(invalid))
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestDump(t *testing.T) {
+	t.Parallel()
+	pieces := "40:" + string(make([]byte, 40))
+	dump, err := Dump([]byte("d8:announce15:http://tracker/4:infod5:filesle6:lengthi10e6:pieces" + pieces + "ee"))
+	assert.NoError(t, err)
+	assert.Equal(t, `{
+  "announce": "http://tracker/"
+  "info": {
+    "files": []
+    "length": 10
+    "pieces": <40 bytes: 00000000000000000000000000000000...>
+  }
+}`, dump)
+
+	dump, err = Dump([]byte("l2:\xff\xfeli1eee"))
+	assert.NoError(t, err)
+	assert.Equal(t, "[\n  <2 bytes: fffe>\n  [\n    1\n  ]\n]", dump)
+}
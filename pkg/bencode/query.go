@@ -0,0 +1,164 @@
+package bencode
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// find advances to the value at the given path, a dot-separated sequence of dictionary keys and list indexes (e.g.
+// "info.files.2.length"), skipping the values outside the path without decoding them. The path leading to the value
+// is kept in r.path, for error reporting.
+func (r *bReader) find(path string) error {
+	for path != "" {
+		var segment string
+		segment, path, _ = strings.Cut(path, ".")
+		if r.pos >= len(r.data) {
+			return r.syntaxError(r.pos, "value", "entry is empty")
+		}
+
+		var err error
+		switch r.data[r.pos] {
+		case 'd':
+			err = r.findKey(segment)
+		case 'l':
+			err = r.findIndex(segment)
+		default:
+			return r.notFoundError(segment)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findKey advances from the start of a dictionary to the value of the given key.
+func (r *bReader) findKey(key string) error {
+	if err := r.enter(); err != nil {
+		return err
+	}
+	r.pos++
+
+	var rawKey []byte
+	for {
+		if r.pos >= len(r.data) {
+			return r.syntaxError(r.pos, "'e'", "eol reached before end of dict")
+		}
+		if r.data[r.pos] == 'e' {
+			return r.notFoundError(key)
+		}
+
+		var err error
+		rawKey, err = r.readKey(rawKey)
+		if err != nil {
+			return err
+		}
+		r.path = append(r.path, pathElem{key: rawKey})
+		if string(rawKey) == key {
+			return nil
+		}
+		_, err = r.skipElement()
+		if err != nil {
+			return err
+		}
+		r.path = r.path[:len(r.path)-1]
+	}
+}
+
+// findIndex advances from the start of a list to its element at the index given by segment.
+func (r *bReader) findIndex(segment string) error {
+	index, err := strconv.Atoi(segment)
+	if err != nil || index < 0 {
+		return r.notFoundError(segment)
+	}
+	if err := r.enter(); err != nil {
+		return err
+	}
+	r.pos++
+
+	for i := 0; ; i++ {
+		if r.pos >= len(r.data) {
+			return r.syntaxError(r.pos, "'e'", "eol reached before end of list")
+		}
+		if r.data[r.pos] == 'e' {
+			return r.notFoundError(segment)
+		}
+
+		r.path = append(r.path, pathElem{index: i})
+		if i == index {
+			return nil
+		}
+		_, err = r.skipElement()
+		if err != nil {
+			return err
+		}
+		r.path = r.path[:len(r.path)-1]
+	}
+}
+
+// notFoundError returns an error wrapping ErrNotFound for the path segment missing at the current path.
+func (r *bReader) notFoundError(segment string) error {
+	if len(r.path) == 0 {
+		return fmt.Errorf("%w: '%s'", ErrNotFound, segment)
+	}
+	return fmt.Errorf("%w: '%s' in %s", ErrNotFound, segment, formatPath(r.path))
+}
+
+// Get returns the raw bencoded value found at the given path of data, a dot-separated sequence of dictionary keys
+// and list indexes, e.g. "info.files.2.length". Only the values along the path are read; the others are skipped
+// without being decoded. An empty path returns the whole value. If the path does not exist, the returned error
+// wraps ErrNotFound.
+func Get(data []byte, path string) (RawMessage, error) {
+	r := newReader(data)
+	err := r.checkInputSize()
+	if err == nil {
+		err = r.find(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not query bencode: %w", err)
+	}
+
+	raw, err := r.skipElement()
+	if err != nil {
+		return nil, fmt.Errorf("could not query bencode: %w", err)
+	}
+	return raw, nil
+}
+
+// UnmarshalPath decodes the value found at the given path of data into v, which must be a pointer, as Unmarshal
+// does. See Get for the path syntax.
+func UnmarshalPath(data []byte, path string, v any) error {
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Ptr || val.IsNil() {
+		return fmt.Errorf("could not query bencode: v must be a non-nil pointer")
+	}
+
+	r := newReader(data)
+	err := r.checkInputSize()
+	if err == nil {
+		err = r.find(path)
+	}
+	if err == nil {
+		err = r.unmarshal(val)
+	}
+	if err != nil {
+		return fmt.Errorf("could not query bencode: %w", err)
+	}
+	return nil
+}
+
+// GetString returns the string found at the given path of data. See Get for the path syntax.
+func GetString(data []byte, path string) (string, error) {
+	var s string
+	err := UnmarshalPath(data, path, &s)
+	return s, err
+}
+
+// GetInt returns the integer found at the given path of data. See Get for the path syntax.
+func GetInt(data []byte, path string) (int64, error) {
+	var i int64
+	err := UnmarshalPath(data, path, &i)
+	return i, err
+}
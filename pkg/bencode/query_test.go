@@ -0,0 +1,60 @@
+package bencode
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testQueryTorrent = "d8:announce15:http://tracker/4:infod5:filesld6:lengthi10e4:pathl1:aeed6:lengthi20e4:pathl1:b" +
+	"eed6:lengthi30e4:pathl1:c1:deee4:name4:test6:pieces20:aaaaaaaaaaaaaaaaaaaaee"
+
+func TestGet(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"announce", "15:http://tracker/"},
+		{"info.files.2.length", "i30e"},
+		{"info.files.2.path", "l1:c1:de"},
+		{"info.files.0", "d6:lengthi10e4:pathl1:aee"},
+		{"info.name", "4:test"},
+		{"", testQueryTorrent},
+	}
+
+	for _, test := range tests {
+		raw, err := Get([]byte(testQueryTorrent), test.path)
+		if assert.NoError(t, err, test.path) {
+			assert.Equal(t, test.expected, string(raw), test.path)
+		}
+	}
+
+	for _, path := range []string{"comment", "info.files.3", "info.files.x", "info.name.x", "announce.0"} {
+		_, err := Get([]byte(testQueryTorrent), path)
+		assert.ErrorIs(t, err, ErrNotFound, path)
+	}
+}
+
+func TestGetTyped(t *testing.T) {
+	t.Parallel()
+	name, err := GetString([]byte(testQueryTorrent), "info.name")
+	assert.NoError(t, err)
+	assert.Equal(t, "test", name)
+
+	length, err := GetInt([]byte(testQueryTorrent), "info.files.1.length")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(20), length)
+
+	var path []string
+	err = UnmarshalPath([]byte(testQueryTorrent), "info.files.2.path", &path)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c", "d"}, path)
+
+	var typeErr *UnmarshalTypeError
+	_, err = GetInt([]byte(testQueryTorrent), "info.files.2.path")
+	if assert.ErrorAs(t, err, &typeErr) {
+		assert.Equal(t, "info.files[2].path", typeErr.Path)
+		assert.Equal(t, int64(113), typeErr.Offset)
+	}
+}
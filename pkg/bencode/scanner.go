@@ -0,0 +1,223 @@
+package bencode
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+)
+
+// TokenKind identifies the kind of a Token.
+type TokenKind int
+
+// Kinds of tokens returned by a Scanner.
+const (
+	// IntToken is an integer, whose Value holds its digits.
+	IntToken TokenKind = iota + 1
+	// StringToken is a string, whose Value holds its content.
+	StringToken
+	// KeyToken is a dictionary key, whose Value holds its content.
+	KeyToken
+	// ListStart is the beginning of a list.
+	ListStart
+	// ListEnd is the end of a list.
+	ListEnd
+	// DictStart is the beginning of a dictionary.
+	DictStart
+	// DictEnd is the end of a dictionary.
+	DictEnd
+)
+
+// String returns the name of the token kind.
+func (k TokenKind) String() string {
+	switch k {
+	case IntToken:
+		return "int"
+	case StringToken:
+		return "string"
+	case KeyToken:
+		return "key"
+	case ListStart:
+		return "list start"
+	case ListEnd:
+		return "list end"
+	case DictStart:
+		return "dict start"
+	case DictEnd:
+		return "dict end"
+	default:
+		return "invalid token"
+	}
+}
+
+// Token is a single bencode token read by a Scanner.
+type Token struct {
+	// Kind identifies the kind of the token.
+	Kind TokenKind
+	// Offset is the position of the token in the input.
+	Offset int64
+	// Value holds the digits of integers and the content of strings and keys, as a subslice of the input which must
+	// be copied if retained after the input is modified. It is nil for other tokens.
+	Value []byte
+}
+
+// Int returns the value of an integer token as an int64.
+func (t Token) Int() (int64, error) {
+	if t.Kind != IntToken {
+		return 0, &UnmarshalTypeError{Value: t.Kind.String(), Type: reflect.TypeOf(int64(0)), Offset: t.Offset}
+	}
+	i, err := strconv.ParseInt(string(t.Value), 10, 64)
+	if err != nil {
+		value := "integer " + string(t.Value)
+		return 0, &UnmarshalTypeError{Value: value, Type: reflect.TypeOf(i), Offset: t.Offset}
+	}
+	return i, nil
+}
+
+// scanFrame holds the state of a list or dictionary being scanned.
+type scanFrame struct {
+	// dict tells whether the container is a dictionary.
+	dict bool
+	// n holds the number of keys and values scanned in the container so far.
+	n int
+}
+
+// Scanner reads bencoded data token by token, without decoding or copying values, so it can be used on hot paths
+// without allocating once created. Tokens of several consecutive top-level values can be read from the same input.
+type Scanner struct {
+	// r reads the tokens from the input.
+	r bReader
+	// stack holds the lists and dictionaries enclosing the current position, innermost last.
+	stack []scanFrame
+	// stackBuf backs stack for shallow documents.
+	stackBuf [16]scanFrame
+}
+
+// NewScanner returns a new Scanner that reads tokens from data, bounding the nesting depth by DefaultLimits.
+func NewScanner(data []byte) *Scanner {
+	s := &Scanner{}
+	s.Reset(data)
+	return s
+}
+
+// Reset makes the Scanner read tokens from data, from its beginning, reusing its state to avoid allocations.
+func (s *Scanner) Reset(data []byte) {
+	s.r = bReader{data: data, limits: DefaultLimits}
+	s.stack = s.stackBuf[:0]
+}
+
+// Offset returns the position in the input of the next token.
+func (s *Scanner) Offset() int64 {
+	return int64(s.r.pos)
+}
+
+// Depth returns the number of lists and dictionaries enclosing the next token.
+func (s *Scanner) Depth() int {
+	return len(s.stack)
+}
+
+// Next reads and returns the next token. It returns io.EOF when the input ends between top-level values, and a
+// *SyntaxError or *LimitError if the input is malformed or too deeply nested.
+func (s *Scanner) Next() (Token, error) {
+	r := &s.r
+	start := r.pos
+	if r.pos >= len(r.data) {
+		if len(s.stack) > 0 {
+			return Token{}, r.syntaxError(r.pos, "'e'", "eol reached before end of element")
+		}
+		return Token{}, io.EOF
+	}
+
+	// Dictionary keys must be strings, followed by a value
+	var frame *scanFrame
+	isKey := false
+	if len(s.stack) > 0 {
+		frame = &s.stack[len(s.stack)-1]
+		isKey = frame.dict && frame.n%2 == 0
+	}
+	c := r.data[r.pos]
+	if isKey && c != 'e' && (c < '0' || c > '9') {
+		return Token{}, r.syntaxError(r.pos, "string key", fmt.Sprintf("invalid character %q in dictionary key", c))
+	}
+
+	switch {
+	case c == 'e':
+		if frame == nil {
+			return Token{}, r.syntaxError(r.pos, "value", "unexpected end delimiter")
+		} else if frame.dict && !isKey {
+			return Token{}, r.syntaxError(r.pos, "value", "missing value for dictionary key")
+		}
+		kind := ListEnd
+		if frame.dict {
+			kind = DictEnd
+		}
+		r.pos++
+		r.depth--
+		s.stack = s.stack[:len(s.stack)-1]
+		s.advance()
+		return Token{Kind: kind, Offset: int64(start)}, nil
+	case c == 'l' || c == 'd':
+		if err := r.enter(); err != nil {
+			return Token{}, err
+		}
+		r.pos++
+		s.stack = append(s.stack, scanFrame{dict: c == 'd'})
+		if c == 'd' {
+			return Token{Kind: DictStart, Offset: int64(start)}, nil
+		}
+		return Token{Kind: ListStart, Offset: int64(start)}, nil
+	case c == 'i':
+		digits, err := r.readInt()
+		if err != nil {
+			return Token{}, err
+		}
+		s.advance()
+		return Token{Kind: IntToken, Offset: int64(start), Value: digits}, nil
+	case c >= '0' && c <= '9':
+		str, err := r.readString()
+		if err != nil {
+			return Token{}, err
+		}
+		s.advance()
+		if isKey {
+			return Token{Kind: KeyToken, Offset: int64(start), Value: str}, nil
+		}
+		return Token{Kind: StringToken, Offset: int64(start), Value: str}, nil
+	default:
+		return Token{}, r.syntaxError(r.pos, "'i', 'l', 'd', or '0'-'9'", fmt.Sprintf("invalid character %q", c))
+	}
+}
+
+// Skip advances over the next whole value, including all the tokens of lists and dictionaries, and returns its raw
+// bytes. Called right after a KeyToken, it skips the value of that key.
+func (s *Scanner) Skip() ([]byte, error) {
+	r := &s.r
+	if r.pos < len(r.data) && r.data[r.pos] == 'e' {
+		return nil, r.syntaxError(r.pos, "value", "unexpected end delimiter")
+	}
+	if r.pos >= len(r.data) && len(s.stack) == 0 {
+		return nil, io.EOF
+	}
+
+	if len(s.stack) > 0 && s.stack[len(s.stack)-1].dict && s.stack[len(s.stack)-1].n%2 == 0 {
+		start := r.pos
+		_, err := s.Next() // dictionary keys are strings
+		if err != nil {
+			return nil, err
+		}
+		return r.data[start:r.pos], nil
+	}
+	raw, err := r.skipElement()
+	if err != nil {
+		return nil, err
+	}
+	s.advance()
+	return raw, nil
+}
+
+// advance counts a complete key or value in the innermost list or dictionary.
+func (s *Scanner) advance() {
+	if len(s.stack) > 0 {
+		s.stack[len(s.stack)-1].n++
+	}
+}
@@ -0,0 +1,127 @@
+package bencode
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanner(t *testing.T) {
+	t.Parallel()
+	s := NewScanner([]byte("d4:listli-1e0:e4:name4:testei42e"))
+	expected := []struct {
+		kind   TokenKind
+		offset int64
+		value  string
+	}{
+		{DictStart, 0, ""},
+		{KeyToken, 1, "list"},
+		{ListStart, 7, ""},
+		{IntToken, 8, "-1"},
+		{StringToken, 12, ""},
+		{ListEnd, 14, ""},
+		{KeyToken, 15, "name"},
+		{StringToken, 21, "test"},
+		{DictEnd, 27, ""},
+		{IntToken, 28, "42"},
+	}
+
+	for _, exp := range expected {
+		tok, err := s.Next()
+		if assert.NoError(t, err) {
+			assert.Equal(t, exp.kind, tok.Kind, exp.kind.String())
+			assert.Equal(t, exp.offset, tok.Offset, exp.kind.String())
+			assert.Equal(t, exp.value, string(tok.Value), exp.kind.String())
+		}
+	}
+	_, err := s.Next()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestScannerSkip(t *testing.T) {
+	t.Parallel()
+	data := []byte("d4:infod6:pieces6:aaaaaae4:name4:teste")
+	s := NewScanner(data)
+
+	tok, err := s.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, DictStart, tok.Kind)
+	tok, err = s.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, "info", string(tok.Value))
+
+	raw, err := s.Skip()
+	assert.NoError(t, err)
+	assert.Equal(t, "d6:pieces6:aaaaaae", string(raw))
+
+	raw, err = s.Skip() // skips the key
+	assert.NoError(t, err)
+	assert.Equal(t, "4:name", string(raw))
+	tok, err = s.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, "test", string(tok.Value))
+
+	_, err = s.Skip()
+	var syntaxErr *SyntaxError
+	assert.ErrorAs(t, err, &syntaxErr)
+	tok, err = s.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, DictEnd, tok.Kind)
+	assert.Equal(t, 0, s.Depth())
+}
+
+func TestScannerErrors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		bCode  string
+		offset int64
+	}{
+		{"di1ei2ee", 1},
+		{"d1:ae", 4},
+		{"e", 0},
+		{"li1e", 4},
+		{"i1x2e", 0},
+		{"5:abc", 5},
+		{"x", 0},
+	}
+
+	for _, test := range tests {
+		s := NewScanner([]byte(test.bCode))
+		var err error
+		for err == nil {
+			_, err = s.Next()
+		}
+
+		var syntaxErr *SyntaxError
+		if assert.ErrorAs(t, err, &syntaxErr, test.bCode) {
+			assert.Equal(t, test.offset, syntaxErr.Offset, test.bCode)
+		}
+	}
+
+	s := NewScanner([]byte("lllee"))
+	s.r.limits.MaxDepth = 2
+	var err error
+	for err == nil {
+		_, err = s.Next()
+	}
+	assert.ErrorIs(t, err, ErrMaxDepth)
+}
+
+func TestScannerAllocs(t *testing.T) {
+	data := []byte("d8:completei12e10:incompletei3e8:intervali1800e5:peers12:abcdefghijkle")
+	s := NewScanner(nil)
+	allocs := testing.AllocsPerRun(100, func() {
+		s.Reset(data)
+		for {
+			tok, err := s.Next()
+			if err != nil {
+				break
+			}
+			if tok.Kind == IntToken {
+				_, _ = tok.Int()
+			}
+		}
+	})
+	assert.Zero(t, allocs)
+}
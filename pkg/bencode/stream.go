@@ -0,0 +1,328 @@
+package bencode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// minRead is the minimum amount of free buffer space, in bytes, requested from the underlying reader on each read.
+const minRead = 512
+
+// errIncomplete is returned by scanState when the available data ends before the scanned value does.
+var errIncomplete = errors.New("unexpected end of input")
+
+// scanState tracks the progress of locating the end of a bencoded value, so scanning can resume after more data
+// becomes available instead of starting over.
+type scanState struct {
+	// pos holds the offset of the next token to be scanned.
+	pos int
+	// depth holds the current nesting level of lists and dictionaries.
+	depth int
+	// baseDepth holds the nesting level the scanned value starts at, so MaxDepth applies to the whole document.
+	baseDepth int
+	// elements holds the number of values scanned so far, dictionary keys excluded.
+	elements int
+	// open holds the kind of each open list or dictionary, telling whether dictionaries expect a key or a value.
+	open []openKind
+	// limits bounds the nesting depth and string lengths of the scanned value.
+	limits Limits
+}
+
+// openKind is the kind of a list or dictionary open while scanning.
+type openKind uint8
+
+// Kinds of open lists and dictionaries.
+const (
+	// openList is a list.
+	openList openKind = iota
+	// openDictKey is a dictionary expecting a key.
+	openDictKey
+	// openDictValue is a dictionary expecting the value of a key.
+	openDictValue
+)
+
+// countValue counts the complete token starting at s.pos, unless it is a dictionary key, and updates what the
+// enclosing dictionary expects next.
+func (s *scanState) countValue() {
+	if n := len(s.open); n > 0 && s.open[n-1] != openList {
+		if s.open[n-1] == openDictKey {
+			s.open[n-1] = openDictValue
+			return
+		}
+		s.open[n-1] = openDictKey
+	}
+	s.elements++
+}
+
+// scan advances over the complete tokens of data, starting at s.pos, until the end of the value is found.
+// Returns the offset right after the value, or errIncomplete if data ends before the value does.
+func (s *scanState) scan(data []byte) (int, error) {
+	for {
+		if s.pos >= len(data) {
+			return 0, errIncomplete
+		}
+
+		c := data[s.pos]
+		if n := len(s.open); n > 0 && s.open[n-1] == openDictKey && c != 'e' && (c < '0' || c > '9') {
+			msg := fmt.Sprintf("invalid character %q in dictionary key", c)
+			return 0, &SyntaxError{Offset: int64(s.pos), Expected: "string key", msg: msg}
+		} else if n > 0 && s.open[n-1] == openDictValue && c == 'e' {
+			return 0, &SyntaxError{Offset: int64(s.pos), Expected: "value", msg: "missing value of dictionary key"}
+		}
+		switch {
+		case c == 'i':
+			end := bytes.IndexByte(data[s.pos+1:], 'e')
+			if end < 0 {
+				return 0, errIncomplete
+			}
+			if !validInt(data[s.pos+1 : s.pos+1+end]) {
+				return 0, &SyntaxError{Offset: int64(s.pos), Expected: "digits", msg: "invalid integer"}
+			}
+			s.countValue()
+			s.pos += end + 2
+		case c == 'l' || c == 'd':
+			s.countValue()
+			s.depth++
+			if s.limits.MaxDepth > 0 && s.depth > s.limits.MaxDepth {
+				return 0, &LimitError{Err: ErrMaxDepth, Limit: int64(s.limits.MaxDepth), Offset: int64(s.pos)}
+			}
+			if c == 'l' {
+				s.open = append(s.open, openList)
+			} else {
+				s.open = append(s.open, openDictKey)
+			}
+			s.pos++
+		case c == 'e':
+			if s.depth == s.baseDepth {
+				return 0, &SyntaxError{Offset: int64(s.pos), Expected: "value", msg: "unexpected end delimiter"}
+			}
+			s.depth--
+			s.open = s.open[:len(s.open)-1]
+			s.pos++
+		case c >= '0' && c <= '9':
+			colon := bytes.IndexByte(data[s.pos:], ':')
+			if colon < 0 {
+				return 0, errIncomplete
+			}
+			lenStr := data[s.pos : s.pos+colon]
+			length, err := strconv.Atoi(string(lenStr))
+			if err != nil || !isDigits(lenStr) {
+				return 0, &SyntaxError{Offset: int64(s.pos), Expected: "string length", msg: "invalid string length"}
+			}
+			if s.limits.MaxStringLength > 0 && length > s.limits.MaxStringLength {
+				limit := int64(s.limits.MaxStringLength)
+				return 0, &LimitError{Err: ErrMaxStringLength, Limit: limit, Offset: int64(s.pos)}
+			}
+			end := s.pos + colon + 1 + length
+			if end > len(data) {
+				return 0, errIncomplete
+			}
+			s.countValue()
+			s.pos = end
+		default:
+			return 0, &SyntaxError{
+				Offset:   int64(s.pos),
+				Expected: "'i', 'l', 'd', or '0'-'9'",
+				msg:      fmt.Sprintf("invalid character %q", c),
+			}
+		}
+
+		if s.depth == s.baseDepth {
+			return s.pos, nil
+		}
+	}
+}
+
+// Decoder reads and decodes successive bencoded values from an input stream.
+type Decoder struct {
+	// r is the underlying reader providing the bencoded stream.
+	r io.Reader
+	// buf holds data read from r that has not been decoded yet, starting at scanp.
+	buf []byte
+	// scanp holds the offset in buf of the first byte not yet consumed by Decode.
+	scanp int
+	// scanned holds the number of bytes discarded from the beginning of buf.
+	scanned int64
+	// err holds the last error returned by r, reported once the buffered data is exhausted.
+	err error
+	// strict rejects values that are not in canonical form.
+	strict bool
+	// limits bounds the resources used to decode each value.
+	limits Limits
+	// useBigInt decodes integers beyond 64 bits into interface values as *big.Int.
+	useBigInt bool
+	// useDict decodes dictionaries into interface values as Dict.
+	useDict bool
+}
+
+// NewDecoder returns a new Decoder that reads from r. The decoder buffers its input and may read data from r
+// beyond the requested values.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r, limits: DefaultLimits}
+}
+
+// Decode reads the next bencoded value from its input and stores it in the value pointed to by v. It returns
+// io.EOF when the input ends cleanly between values, and io.ErrUnexpectedEOF when it ends inside a value.
+func (d *Decoder) Decode(v any) error {
+	n, err := d.readValue()
+	if err != nil {
+		return err
+	}
+
+	r := newReader(d.buf[d.scanp : d.scanp+n])
+	r.base = d.InputOffset()
+	r.strict = d.strict
+	r.limits = d.limits
+	r.useBigInt = d.useBigInt
+	r.useDict = d.useDict
+	d.scanp += n
+	return unmarshal(r, v)
+}
+
+// DisallowNonCanonical causes the Decoder to return a *NonCanonicalError when a value is not in canonical form:
+// unsorted or duplicate dictionary keys, negative zero or leading zeros in string lengths.
+func (d *Decoder) DisallowNonCanonical() {
+	d.strict = true
+}
+
+// UseBigInt causes the Decoder to decode integers that do not fit in 64 bits into an interface{} as *big.Int,
+// instead of failing with an *UnmarshalTypeError. Fields of type big.Int or *big.Int always accept such integers.
+func (d *Decoder) UseBigInt() {
+	d.useBigInt = true
+}
+
+// UseDict causes the Decoder to decode dictionaries into an interface{} as Dict instead of map[string]interface{},
+// keeping the order of their keys and any duplicate keys, so they can be re-encoded without changes.
+func (d *Decoder) UseDict() {
+	d.useDict = true
+}
+
+// SetLimits sets the limits bounding the resources used to decode each value, which should be restricted when
+// reading from untrusted sources. MaxInputSize bounds the amount of data buffered for a single value.
+func (d *Decoder) SetLimits(limits Limits) {
+	d.limits = limits
+}
+
+// Buffered returns a reader of the data remaining in the Decoder's buffer, which is valid until the next call to
+// Decode.
+func (d *Decoder) Buffered() io.Reader {
+	return bytes.NewReader(d.buf[d.scanp:])
+}
+
+// InputOffset returns the offset in the input stream of the first byte not yet consumed by Decode.
+func (d *Decoder) InputOffset() int64 {
+	return d.scanned + int64(d.scanp)
+}
+
+// readValue reads from the underlying reader until buf holds a complete value starting at scanp, and returns its
+// length in bytes.
+func (d *Decoder) readValue() (int, error) {
+	scan := scanState{pos: d.scanp, limits: d.limits}
+	for {
+		end, err := scan.scan(d.buf)
+		var syntaxErr *SyntaxError
+		var limitErr *LimitError
+		if err == nil && d.exceedsInputSize(end-d.scanp) {
+			return 0, d.inputSizeError()
+		} else if err == nil {
+			return end - d.scanp, nil
+		} else if errors.As(err, &limitErr) {
+			limitErr.Offset += d.scanned
+			return 0, fmt.Errorf("could not decode bencode: %w", err)
+		} else if errors.As(err, &syntaxErr) {
+			// Decode buffered data to report the error path, falling back to the scan error
+			r := newReader(d.buf[d.scanp:])
+			r.base = d.InputOffset()
+			if _, decodeErr := r.decodeElement(); errors.As(decodeErr, &syntaxErr) {
+				return 0, fmt.Errorf("could not decode bencode: %w", decodeErr)
+			}
+			syntaxErr.Offset += d.scanned
+			return 0, fmt.Errorf("could not decode bencode: %w", err)
+		} else if !errors.Is(err, errIncomplete) {
+			return 0, fmt.Errorf("could not decode bencode: %w", err)
+		}
+
+		// Stop reading once the incomplete value reaches the maximum input size
+		if d.exceedsInputSize(len(d.buf) - d.scanp) {
+			return 0, d.inputSizeError()
+		}
+
+		// Report reader errors only after all buffered data was scanned
+		if d.err != nil {
+			if d.err == io.EOF {
+				if d.scanp == len(d.buf) {
+					return 0, io.EOF
+				}
+				return 0, io.ErrUnexpectedEOF
+			}
+			return 0, d.err
+		}
+
+		scan.pos -= d.scanp
+		d.err = d.refill()
+		scan.pos += d.scanp
+	}
+}
+
+// exceedsInputSize reports whether n bytes exceed the maximum input size of a single value.
+func (d *Decoder) exceedsInputSize(n int) bool {
+	return d.limits.MaxInputSize > 0 && int64(n) > d.limits.MaxInputSize
+}
+
+// inputSizeError returns the error reported when the value at the current offset exceeds the maximum input size.
+func (d *Decoder) inputSizeError() error {
+	limitErr := &LimitError{Err: ErrMaxInputSize, Limit: d.limits.MaxInputSize, Offset: d.InputOffset()}
+	return fmt.Errorf("could not decode bencode: %w", limitErr)
+}
+
+// refill discards consumed data, grows the buffer if needed and reads more data from the underlying reader.
+func (d *Decoder) refill() error {
+	// Move unread data to the beginning of the buffer
+	if d.scanp > 0 {
+		d.scanned += int64(d.scanp)
+		n := copy(d.buf, d.buf[d.scanp:])
+		d.buf = d.buf[:n]
+		d.scanp = 0
+	}
+
+	// Grow buffer if there is not enough free space
+	if cap(d.buf)-len(d.buf) < minRead {
+		newBuf := make([]byte, len(d.buf), 2*cap(d.buf)+minRead)
+		copy(newBuf, d.buf)
+		d.buf = newBuf
+	}
+
+	n, err := d.r.Read(d.buf[len(d.buf):cap(d.buf)])
+	d.buf = d.buf[:len(d.buf)+n]
+	return err
+}
+
+// Encoder writes bencoded values to an output stream.
+type Encoder struct {
+	// w is the underlying writer receiving the encoded values.
+	w io.Writer
+	// enc holds the buffer values are encoded into, which is reused across calls to Encode.
+	enc bWriter
+}
+
+// NewEncoder returns a new Encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode writes the bencode encoding of v to the stream.
+func (e *Encoder) Encode(v any) error {
+	err := e.enc.encodeValue(v)
+	if err != nil {
+		return fmt.Errorf("marshalling failed: %w", err)
+	}
+
+	_, err = e.w.Write(e.enc.buf)
+	if err != nil {
+		return fmt.Errorf("could not write bencode: %w", err)
+	}
+	return nil
+}
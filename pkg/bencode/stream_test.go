@@ -0,0 +1,69 @@
+package bencode
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncoderAndDecoder(t *testing.T) {
+	t.Parallel()
+	for range *SimNumbers {
+		seed := gofakeit.Int64()
+		if gofakeit.Seed(seed) != nil {
+			continue
+		}
+
+		// Encode a random number of values to the same stream
+		values := make([]testData[interface{}], gofakeit.IntN(5)+1)
+		expected := ""
+		buf := &bytes.Buffer{}
+		enc := NewEncoder(buf)
+		for i := range values {
+			values[i] = switchGenType(gofakeit.IntN(4), 0)
+			expected += values[i].bCode
+			if !assert.NoError(t, enc.Encode(values[i].data), FormatSeed(seed)) {
+				return
+			}
+		}
+		if !assert.Equal(t, expected, buf.String(), FormatSeed(seed)) {
+			continue
+		}
+
+		// Decode them back one byte at a time
+		dec := NewDecoder(iotest.OneByteReader(buf))
+		for _, val := range values {
+			var decoded interface{}
+			if assert.NoError(t, dec.Decode(&decoded), FormatInfo(seed, expected)) {
+				assert.Equal(t, val.data, decoded, FormatInfo(seed, expected))
+			}
+		}
+		var decoded interface{}
+		assert.Equal(t, io.EOF, dec.Decode(&decoded), FormatInfo(seed, expected))
+		assert.Equal(t, int64(len(expected)), dec.InputOffset(), FormatInfo(seed, expected))
+	}
+}
+
+func TestDecoderUnexpectedEOF(t *testing.T) {
+	t.Parallel()
+	var decoded interface{}
+	dec := NewDecoder(strings.NewReader("d3:key5:valu"))
+	assert.Equal(t, io.ErrUnexpectedEOF, dec.Decode(&decoded))
+}
+
+func TestDecoderBuffered(t *testing.T) {
+	t.Parallel()
+	var decoded map[string]interface{}
+	dec := NewDecoder(strings.NewReader("d8:msg_typei1e5:piecei0ee" + "raw piece data"))
+	if assert.NoError(t, dec.Decode(&decoded)) {
+		rest, err := io.ReadAll(dec.Buffered())
+		if assert.NoError(t, err) {
+			assert.Equal(t, "raw piece data", string(rest))
+		}
+	}
+}
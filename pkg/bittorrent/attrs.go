@@ -0,0 +1,102 @@
+package bittorrent
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileAttr holds the attributes of a file as a string of flags (BEP 47). Unknown flags are ignored.
+type FileAttr string
+
+// IsPadding reports whether the file is a padding file, filled with zeros to align the next file to a piece
+// boundary, which is not part of the torrent content.
+func (a FileAttr) IsPadding() bool {
+	return strings.ContainsRune(string(a), 'p')
+}
+
+// IsExecutable reports whether the file should be made executable.
+func (a FileAttr) IsExecutable() bool {
+	return strings.ContainsRune(string(a), 'x')
+}
+
+// IsHidden reports whether the file should be hidden.
+func (a FileAttr) IsHidden() bool {
+	return strings.ContainsRune(string(a), 'h')
+}
+
+// IsSymlink reports whether the file is a symlink, whose target is given by its symlink path.
+func (a FileAttr) IsSymlink() bool {
+	return strings.ContainsRune(string(a), 'l')
+}
+
+// setAttributes sets the attributes of the file from its flags and the path components of its symlink target, which
+// are relative to root. Symlink paths are checked like file paths, so they cannot point outside of the torrent.
+func (f *FileMetadata) setAttributes(attr FileAttr, symlinkPath []string, root string) error {
+	f.Padding, f.Executable, f.Hidden = attr.IsPadding(), attr.IsExecutable(), attr.IsHidden()
+	if !attr.IsSymlink() {
+		return nil
+	}
+
+	if len(symlinkPath) == 0 {
+		return fmt.Errorf("missing symlink path")
+	}
+	for _, component := range symlinkPath {
+		if !validPathComponent(component) {
+			return fmt.Errorf("invalid symlink path component '%s'", component)
+		}
+	}
+	f.SymlinkPath = filepath.Join(append([]string{root}, symlinkPath...)...)
+	return nil
+}
+
+// IsStored reports whether the content of the file is written to disk, which is not the case for padding files and
+// symlinks.
+func (f *FileMetadata) IsStored() bool {
+	return !f.Padding && f.SymlinkPath == ""
+}
+
+// ApplyAttributes applies the attributes of the files of a completed download in the given directory: it makes
+// executable files executable by those who can read them, and creates symlinks, replacing any file at their path.
+// Padding files are never written to disk, so they are skipped, and hidden files are left as they are, as hiding is
+// up to their names on most systems.
+func (meta *TorrentMetadata) ApplyAttributes(dir string) error {
+	var errs []error
+	for _, file := range meta.Files {
+		path := filepath.Join(dir, file.Path)
+		switch {
+		case file.Padding:
+			continue
+		case file.SymlinkPath != "":
+			target, err := filepath.Rel(filepath.Dir(path), filepath.Join(dir, file.SymlinkPath))
+			if err == nil {
+				err = os.MkdirAll(filepath.Dir(path), 0o755)
+			}
+			if err == nil {
+				err = os.Remove(path)
+				if errors.Is(err, fs.ErrNotExist) {
+					err = nil
+				}
+			}
+			if err == nil {
+				err = os.Symlink(target, path)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("could not create symlink %s: %w", file.Path, err))
+			}
+		case file.Executable:
+			stat, err := os.Stat(path)
+			if err == nil {
+				mode := stat.Mode().Perm()
+				err = os.Chmod(path, mode|(mode&0o444)>>2)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("could not make %s executable: %w", file.Path, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,82 @@
+package bittorrent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// attrTestTorrent returns a torrent holding an executable script, a padding file, a hidden file and a symlink.
+func attrTestTorrent(t *testing.T, symlinkPath []interface{}) *TorrentFile {
+	return writeTestTorrent(t, map[string]interface{}{
+		"info": map[string]interface{}{
+			"name":         "tools",
+			"piece length": 16,
+			"pieces":       strings.Repeat("a", 3*20),
+			"files": []interface{}{
+				map[string]interface{}{"length": 10, "path": []interface{}{"bin", "run.sh"}, "attr": "x"},
+				map[string]interface{}{"length": 6, "path": []interface{}{".pad", "6"}, "attr": "p"},
+				map[string]interface{}{"length": 20, "path": []interface{}{".config"}, "attr": "h"},
+				map[string]interface{}{
+					"length": 0, "path": []interface{}{"run"}, "attr": "l", "symlink path": symlinkPath,
+				},
+			},
+		},
+	})
+}
+
+func TestFileAttributes(t *testing.T) {
+	t.Parallel()
+	torrent := attrTestTorrent(t, []interface{}{"bin", "run.sh"})
+	meta, err := torrent.GetMetadata()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []FileMetadata{
+		{Path: filepath.Join("tools", "bin", "run.sh"), Length: 10, Executable: true},
+		{Path: filepath.Join("tools", ".pad", "6"), Length: 6, Offset: 10, Padding: true},
+		{Path: filepath.Join("tools", ".config"), Length: 20, Offset: 16, Hidden: true},
+		{Path: filepath.Join("tools", "run"), Offset: 36, SymlinkPath: filepath.Join("tools", "bin", "run.sh")},
+	}, meta.Files)
+	assert.True(t, meta.Files[0].IsStored())
+	assert.False(t, meta.Files[1].IsStored())
+	assert.False(t, meta.Files[3].IsStored())
+
+	// Write the stored files as a download would, then apply the attributes
+	dir := t.TempDir()
+	for _, file := range meta.Files {
+		if file.IsStored() {
+			path := filepath.Join(dir, file.Path)
+			assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+			assert.NoError(t, os.WriteFile(path, make([]byte, file.Length), 0o644))
+		}
+	}
+	if !assert.NoError(t, meta.ApplyAttributes(dir)) {
+		return
+	}
+
+	stat, err := os.Stat(filepath.Join(dir, "tools", "bin", "run.sh"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, os.FileMode(0o755), stat.Mode().Perm())
+	}
+	target, err := os.Readlink(filepath.Join(dir, "tools", "run"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, filepath.Join("bin", "run.sh"), target)
+	}
+	_, err = os.Stat(filepath.Join(dir, "tools", ".pad"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestFileAttributesInvalidSymlink(t *testing.T) {
+	t.Parallel()
+	torrent := attrTestTorrent(t, []interface{}{"..", "etc", "passwd"})
+	_, err := torrent.GetMetadata()
+	assert.ErrorContains(t, err, "invalid symlink path component '..'")
+
+	torrent.Info.Files[3].SymlinkPath = nil
+	_, err = torrent.GetMetadata()
+	assert.ErrorContains(t, err, "missing symlink path")
+}
@@ -0,0 +1,406 @@
+package bittorrent
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"github.com/GFLdev/gorrent/pkg/bencode"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Bounds of the piece lengths chosen by AutoPieceLength.
+const (
+	// MinPieceLength is the smallest piece length chosen automatically, in bytes.
+	MinPieceLength = 16 << 10
+	// MaxPieceLength is the largest piece length chosen automatically, in bytes.
+	MaxPieceLength = 16 << 20
+	// targetPieces is the number of pieces AutoPieceLength aims not to exceed.
+	targetPieces = 1500
+)
+
+// BuildOptions holds the optional metadata and settings used to build a torrent.
+type BuildOptions struct {
+	// PieceLength is the size of each piece in bytes, which must be a power of two of at least 16 KiB. If zero, it is
+	// chosen by AutoPieceLength.
+	PieceLength int
+	// Announce is the primary tracker URL.
+	Announce string
+	// AnnounceList holds tiers of tracker URLs (BEP 12).
+	AnnounceList [][]string
+	// Comment is an optional textual description.
+	Comment string
+	// CreatedBy is the name and version of the application creating the torrent.
+	CreatedBy string
+	// CreationDate is the creation time of the torrent. If zero, the current time is used.
+	CreationDate time.Time
+	// Private restricts peers to those returned by the trackers (BEP 27).
+	Private bool
+	// Source identifies the origin of the torrent, usually a private tracker.
+	Source string
+	// WebSeeds lists web seed URLs (BEP 19).
+	WebSeeds []string
+	// Workers is the number of pieces hashed in parallel. If zero, the number of CPUs is used.
+	Workers int
+}
+
+// buildFile holds a file to be included in a torrent.
+type buildFile struct {
+	// path is the path of the file in the file system.
+	path string
+	// components holds the path of the file relative to the torrent directory.
+	components []string
+	// length is the size of the file in bytes.
+	length int64
+}
+
+// AutoPieceLength returns a piece length suited to content of the given total size: the smallest power of two
+// between MinPieceLength and MaxPieceLength which keeps the number of pieces around 1500 at most.
+func AutoPieceLength(total int64) int {
+	length := MinPieceLength
+	for length < MaxPieceLength && total/int64(length) > targetPieces {
+		length *= 2
+	}
+	return length
+}
+
+// BuildTorrent builds a torrent of the file or directory at root, hashing its pieces in parallel. Directories are
+// walked recursively, including their regular files in lexical order and skipping other kinds of files.
+func BuildTorrent(root string, opts BuildOptions) (*TorrentFile, error) {
+	if opts.PieceLength != 0 && (opts.PieceLength < MinPieceLength || opts.PieceLength&(opts.PieceLength-1) != 0) {
+		return nil, fmt.Errorf("could not build torrent: invalid piece length %d", opts.PieceLength)
+	}
+
+	// Resolve root, so relative paths such as "." are named after the directory they refer to
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("could not build torrent: %w", err)
+	}
+	name := filepath.Base(root)
+	if !validPathComponent(name) {
+		return nil, fmt.Errorf("could not build torrent: invalid torrent name '%s'", name)
+	}
+	files, multiFile, err := walkContent(root)
+	if err != nil {
+		return nil, fmt.Errorf("could not build torrent: %w", err)
+	}
+	var total int64
+	for _, file := range files {
+		total += file.length
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("could not build torrent: %s has no content", root)
+	}
+
+	// Hash pieces
+	pieceLength := opts.PieceLength
+	if pieceLength == 0 {
+		pieceLength = AutoPieceLength(total)
+	}
+	pieces, err := hashPieces(files, total, pieceLength, opts.Workers)
+	if err != nil {
+		return nil, fmt.Errorf("could not build torrent: %w", err)
+	}
+
+	// Fill metainfo
+	creationDate := opts.CreationDate
+	if creationDate.IsZero() {
+		creationDate = time.Now()
+	}
+	t := &TorrentFile{
+		Announce:     opts.Announce,
+		AnnounceList: opts.AnnounceList,
+		Comment:      opts.Comment,
+		CreatedBy:    opts.CreatedBy,
+		CreationDate: time.Unix(creationDate.Unix(), 0),
+		URLList:      opts.WebSeeds,
+		Info: TorrentInfo{
+			Pieces:      string(pieces),
+			PieceLength: pieceLength,
+			Name:        name,
+			Private:     opts.Private,
+			Source:      opts.Source,
+		},
+	}
+	if multiFile {
+		t.Info.Files = make([]FileInfo, len(files))
+		for i, file := range files {
+			t.Info.Files[i] = FileInfo{Length: file.length, Path: file.components}
+		}
+	} else {
+		t.Info.Length = total
+	}
+	return t, nil
+}
+
+// walkContent lists the regular files of the file or directory at root, and reports whether root is a directory.
+func walkContent(root string) ([]buildFile, bool, error) {
+	stat, err := os.Stat(root)
+	if err != nil {
+		return nil, false, err
+	}
+	if !stat.IsDir() {
+		if !stat.Mode().IsRegular() {
+			return nil, false, fmt.Errorf("%s is not a regular file", root)
+		}
+		return []buildFile{{path: root, length: stat.Size()}}, false, nil
+	}
+
+	var files []buildFile
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, buildFile{
+			path:       path,
+			components: strings.Split(filepath.ToSlash(rel), "/"),
+			length:     info.Size(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if len(files) == 0 {
+		return nil, false, fmt.Errorf("%s has no files", root)
+	}
+	return files, true, nil
+}
+
+// hashPieces computes the SHA-1 hashes of the pieces of the concatenated content of files, using the given number
+// of workers, and returns them concatenated.
+func hashPieces(files []buildFile, total int64, pieceLength int, workers int) ([]byte, error) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	numPieces := int((total + int64(pieceLength) - 1) / int64(pieceLength))
+	pieces := make([]byte, numPieces*sha1.Size)
+
+	indexes := make(chan int)
+	errs := make(chan error, workers)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := newContentReader(files)
+			defer r.Close()
+
+			buf := make([]byte, pieceLength)
+			for i := range indexes {
+				n := min(int64(pieceLength), total-int64(i)*int64(pieceLength))
+				_, err := r.ReadAt(buf[:n], int64(i)*int64(pieceLength))
+				if err != nil {
+					errs <- fmt.Errorf("could not read piece %d: %w", i, err)
+					for range indexes { // drain remaining pieces
+					}
+					return
+				}
+				hash := sha1.Sum(buf[:n])
+				copy(pieces[i*sha1.Size:], hash[:])
+			}
+		}()
+	}
+
+	for i := range numPieces {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	close(errs)
+	return pieces, <-errs
+}
+
+// contentReader reads the concatenated content of several files, opening them on demand.
+type contentReader struct {
+	// files holds the files, in the order their content is concatenated.
+	files []buildFile
+	// opened holds the files opened so far, by index.
+	opened map[int]*os.File
+}
+
+// newContentReader returns a reader of the concatenated content of files.
+func newContentReader(files []buildFile) *contentReader {
+	return &contentReader{files: files, opened: make(map[int]*os.File)}
+}
+
+// ReadAt reads len(p) bytes starting at offset off of the concatenated content.
+func (r *contentReader) ReadAt(p []byte, off int64) (int, error) {
+	n := 0
+	var start int64
+	for i, file := range r.files {
+		end := start + file.length
+		if len(p) == n {
+			break
+		}
+		if off+int64(n) >= end {
+			start = end
+			continue
+		}
+
+		f, ok := r.opened[i]
+		if !ok {
+			var err error
+			f, err = os.Open(file.path)
+			if err != nil {
+				return n, err
+			}
+			r.opened[i] = f
+		}
+
+		chunk := p[n:min(len(p), n+int(end-off-int64(n)))]
+		read, err := f.ReadAt(chunk, off+int64(n)-start)
+		n += read
+		if err != nil && !(errors.Is(err, io.EOF) && read == len(chunk)) {
+			return n, fmt.Errorf("could not read %s: %w", file.path, err)
+		}
+		start = end
+	}
+
+	if n < len(p) {
+		return n, io.ErrUnexpectedEOF
+	}
+	return n, nil
+}
+
+// Close closes the opened files.
+func (r *contentReader) Close() error {
+	var errs []error
+	for _, f := range r.opened {
+		errs = append(errs, f.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// WriteTorrent writes the torrent to a .torrent file at the given path, in canonical bencode form, keeping the exact
+// bytes of the info dictionary of torrents parsed from a file, so their info hash does not change, and failing if that
+// info dictionary was modified. The torrent is first written to a temporary file, verified by parsing it back and
+// checking that it keeps its metadata and info hash, and only then moved to the given path.
+func (t *TorrentFile) WriteTorrent(torrentPath string) error {
+	data, err := t.encode()
+	if err != nil {
+		return fmt.Errorf("could not write torrent file: %w", err)
+	}
+	return writeVerified(torrentPath, data, func(tmpPath string) error {
+		err := t.verifyWritten(tmpPath)
+		if err != nil {
+			return fmt.Errorf("could not verify torrent file: %w", err)
+		}
+		return nil
+	})
+}
+
+// writeVerified writes data to a temporary file next to the given path, checks it with verify, and only then moves it
+// to the given path, so a failed verification leaves any existing file untouched. Errors returned by verify are
+// returned as they are.
+func writeVerified(torrentPath string, data []byte, verify func(tmpPath string) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(torrentPath), "."+filepath.Base(torrentPath)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("could not write torrent file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // no-op once renamed
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("could not write torrent file: %w", err)
+	}
+
+	err = verify(tmpPath)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmpPath, torrentPath)
+	if err != nil {
+		return fmt.Errorf("could not write torrent file: %w", err)
+	}
+	return nil
+}
+
+// encode bencodes the torrent in canonical form, with the exact bytes of its info dictionary if it was parsed from a
+// file, as re-encoding it would drop undeclared keys. Fails if the info dictionary of a parsed torrent was modified,
+// as its changes would be lost.
+func (t *TorrentFile) encode() ([]byte, error) {
+	data, err := bencode.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	if !bencode.IsCanonical(data) {
+		return nil, fmt.Errorf("encoding is not canonical")
+	}
+	if len(t.rawInfo) == 0 {
+		return data, nil
+	}
+
+	// Compare info dictionary with the one parsed from the original bytes
+	var original TorrentInfo
+	err = bencode.Unmarshal(t.rawInfo, &original)
+	if err != nil {
+		return nil, err
+	}
+	originalInfo, err := bencode.Marshal(&original)
+	if err != nil {
+		return nil, err
+	}
+	info, err := bencode.Marshal(&t.Info)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(info, originalInfo) {
+		return nil, fmt.Errorf("info dictionary was modified after parsing, so its original bytes cannot be written")
+	}
+
+	var dict bencode.Dict
+	err = bencode.Unmarshal(data, &dict)
+	if err != nil {
+		return nil, err
+	}
+	dict.Set("info", t.rawInfo)
+	return bencode.Encode(dict)
+}
+
+// verifyWritten parses the torrent file written at the given path, checking that it keeps the metadata and info
+// hash of the torrent.
+func (t *TorrentFile) verifyWritten(torrentPath string) error {
+	parsed, err := TorrentFromFile(torrentPath)
+	if err != nil {
+		return err
+	}
+	expected, err := t.GetMetadata()
+	if err != nil {
+		return err
+	}
+	actual, err := parsed.GetMetadata()
+	if err != nil {
+		return err
+	}
+	if actual.InfoHash != expected.InfoHash || actual.Length != expected.Length ||
+		len(actual.PieceHashes) != len(expected.PieceHashes) || len(actual.Files) != len(expected.Files) {
+		return fmt.Errorf("metadata changed after writing")
+	}
+	if len(t.rawInfo) > 0 && !bytes.Equal(parsed.rawInfo, t.rawInfo) {
+		return fmt.Errorf("info dictionary changed after writing")
+	}
+	return nil
+}
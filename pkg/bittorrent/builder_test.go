@@ -0,0 +1,199 @@
+package bittorrent
+
+import (
+	"crypto/sha1"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutoPieceLength(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, MinPieceLength, AutoPieceLength(1))
+	assert.Equal(t, 256<<10, AutoPieceLength(300<<20))
+	assert.Equal(t, MaxPieceLength, AutoPieceLength(1<<40))
+}
+
+func TestBuildTorrent(t *testing.T) {
+	t.Parallel()
+	root := filepath.Join(t.TempDir(), "content")
+	content := map[string][]byte{
+		filepath.Join("a", "first.bin"): make([]byte, 20000),
+		filepath.Join("b.txt"):          []byte("hello"),
+		filepath.Join("c", "empty"):     {},
+		filepath.Join("c", "last.bin"):  make([]byte, 30000),
+	}
+	for i := range content[filepath.Join("c", "last.bin")] {
+		content[filepath.Join("c", "last.bin")][i] = byte(i)
+	}
+	for path, data := range content {
+		assert.NoError(t, os.MkdirAll(filepath.Join(root, filepath.Dir(path)), 0o755))
+		assert.NoError(t, os.WriteFile(filepath.Join(root, path), data, 0o644))
+	}
+
+	torrent, err := BuildTorrent(root, BuildOptions{
+		Announce:     "http://tracker.example.com/announce",
+		AnnounceList: [][]string{{"http://tracker.example.com/announce"}, {"udp://backup.example.com:80"}},
+		Comment:      "test",
+		CreatedBy:    "gorrent",
+		CreationDate: time.Unix(1700000000, 0),
+		Private:      true,
+		Source:       "TEST",
+		Workers:      3,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// Check pieces against the concatenated content
+	var concatenated []byte
+	for _, path := range []string{filepath.Join("a", "first.bin"), "b.txt", filepath.Join("c", "empty"),
+		filepath.Join("c", "last.bin")} {
+		concatenated = append(concatenated, content[path]...)
+	}
+	assert.Equal(t, MinPieceLength, torrent.Info.PieceLength)
+	var pieces []byte
+	for i := 0; i < len(concatenated); i += MinPieceLength {
+		hash := sha1.Sum(concatenated[i:min(len(concatenated), i+MinPieceLength)])
+		pieces = append(pieces, hash[:]...)
+	}
+	assert.Equal(t, string(pieces), torrent.Info.Pieces)
+	assert.Equal(t, []FileInfo{
+		{Length: 20000, Path: []string{"a", "first.bin"}},
+		{Length: 5, Path: []string{"b.txt"}},
+		{Length: 0, Path: []string{"c", "empty"}},
+		{Length: 30000, Path: []string{"c", "last.bin"}},
+	}, torrent.Info.Files)
+
+	// Write and parse back
+	torrentPath := filepath.Join(t.TempDir(), "content.torrent")
+	if assert.NoError(t, torrent.WriteTorrent(torrentPath)) {
+		parsed, err := TorrentFromFile(torrentPath)
+		assert.NoError(t, err)
+		meta, err := parsed.GetMetadata()
+		if assert.NoError(t, err) {
+			assert.Equal(t, "content", meta.Name)
+			assert.Equal(t, int64(len(concatenated)), meta.Length)
+			assert.True(t, meta.Private)
+			assert.Equal(t, "TEST", meta.Source)
+			assert.Equal(t, time.Unix(1700000000, 0), meta.CreationDate)
+			assert.Equal(t, torrent.AnnounceList, meta.AnnounceList)
+		}
+	}
+}
+
+func TestBuildTorrentSingleFile(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "file.bin")
+	assert.NoError(t, os.WriteFile(path, make([]byte, 100000), 0o644))
+
+	torrent, err := BuildTorrent(path, BuildOptions{PieceLength: 32 << 10})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "file.bin", torrent.Info.Name)
+		assert.Equal(t, int64(100000), torrent.Info.Length)
+		assert.False(t, torrent.Info.IsMultiFile())
+		assert.Len(t, torrent.Info.Pieces, 4*20)
+		assert.NoError(t, torrent.WriteTorrent(filepath.Join(t.TempDir(), "file.torrent")))
+	}
+
+	_, err = BuildTorrent(path, BuildOptions{PieceLength: 1000})
+	assert.Error(t, err)
+	_, err = BuildTorrent(t.TempDir(), BuildOptions{})
+	assert.Error(t, err)
+}
+
+func TestBuildTorrentEditInfo(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "file.bin")
+	assert.NoError(t, os.WriteFile(path, make([]byte, 100), 0o644))
+	torrent, err := BuildTorrent(path, BuildOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	hash, err := torrent.InfoHash()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// Info dictionaries of built torrents are encoded when written, so they can still be changed
+	torrent.Info.Private = true
+	torrent.Info.Source = "X"
+	editedHash, err := torrent.InfoHash()
+	if assert.NoError(t, err) {
+		assert.NotEqual(t, hash, editedHash)
+	}
+	torrentPath := filepath.Join(t.TempDir(), "file.torrent")
+	if assert.NoError(t, torrent.WriteTorrent(torrentPath)) {
+		parsed, err := TorrentFromFile(torrentPath)
+		if assert.NoError(t, err) {
+			assert.True(t, parsed.Info.Private)
+			assert.Equal(t, "X", parsed.Info.Source)
+			parsedHash, _ := parsed.InfoHash()
+			assert.Equal(t, editedHash, parsedHash)
+		}
+	}
+}
+
+func TestWriteTorrentKeepsInfo(t *testing.T) {
+	t.Parallel()
+	torrent := writeTestTorrent(t, map[string]interface{}{
+		"announce": "http://tracker.example.com/announce",
+		"info": map[string]interface{}{
+			"name":         "file.bin",
+			"length":       20,
+			"piece length": 16,
+			"pieces":       strings.Repeat("a", 2*20),
+			"x-unknown":    "kept",
+		},
+	})
+	hash, err := torrent.InfoHash()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	torrentPath := filepath.Join(t.TempDir(), "file.torrent")
+	torrent.Comment = "rewritten"
+	if assert.NoError(t, torrent.WriteTorrent(torrentPath)) {
+		parsed, err := TorrentFromFile(torrentPath)
+		if assert.NoError(t, err) {
+			parsedHash, _ := parsed.InfoHash()
+			assert.Equal(t, hash, parsedHash)
+			assert.Equal(t, "rewritten", parsed.Comment)
+		}
+	}
+
+	// Changes to the info dictionary of a parsed torrent cannot be written, leaving the existing file untouched
+	before, _ := os.ReadFile(torrentPath)
+	torrent.Info.Private = true
+	assert.ErrorContains(t, torrent.WriteTorrent(torrentPath), "info dictionary was modified")
+	after, _ := os.ReadFile(torrentPath)
+	assert.Equal(t, before, after)
+	entries, _ := os.ReadDir(filepath.Dir(torrentPath))
+	assert.Len(t, entries, 1)
+}
+
+func TestBuildTorrentRelativeRoot(t *testing.T) {
+	// Not parallel, as it changes the working directory
+	root := filepath.Join(t.TempDir(), "content")
+	assert.NoError(t, os.MkdirAll(root, 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "file.bin"), []byte("hello"), 0o644))
+	wd, err := os.Getwd()
+	if !assert.NoError(t, err) || !assert.NoError(t, os.Chdir(root)) {
+		return
+	}
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+
+	torrent, err := BuildTorrent(".", BuildOptions{})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "content", torrent.Info.Name)
+		_, err = torrent.GetMetadata()
+		assert.NoError(t, err)
+	}
+
+	_, err = BuildTorrent(string(filepath.Separator), BuildOptions{})
+	assert.ErrorContains(t, err, "invalid torrent name")
+}
@@ -0,0 +1,163 @@
+package bittorrent
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"github.com/GFLdev/gorrent/pkg/bencode"
+	"github.com/GFLdev/gorrent/pkg/utils"
+	"slices"
+	"strings"
+)
+
+// TorrentEditor edits the keys of the outer dictionary of a torrent file, such as its trackers, comment or web
+// seeds, while keeping the bytes of its info dictionary untouched, so the info hash of the torrent does not change.
+// Keys of the info dictionary, such as "source", are part of the info hash and cannot be edited.
+type TorrentEditor struct {
+	// dict holds the entries of the outer dictionary, in their original order, with the info dictionary as raw bytes.
+	dict bencode.Dict
+	// canonical tells whether the keys of the outer dictionary were sorted, to keep them sorted when editing it.
+	canonical bool
+	// infoHash is the info hash of the torrent before editing.
+	infoHash []byte
+}
+
+// EditResult reports the info hash of an edited torrent before and after editing, which are expected to be equal.
+type EditResult struct {
+	// InfoHashBefore is the info hash of the torrent file before editing, in hexadecimal.
+	InfoHashBefore string
+	// InfoHashAfter is the info hash of the edited torrent file, in hexadecimal.
+	InfoHashAfter string
+}
+
+// EditTorrent reads the torrent file at the given path to be edited.
+func EditTorrent(torrentPath string) (*TorrentEditor, error) {
+	torrent, err := TorrentFromFile(torrentPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not edit torrent: %w", err)
+	}
+	infoHash, err := torrent.InfoHash()
+	if err != nil {
+		return nil, fmt.Errorf("could not edit torrent: %w", err)
+	}
+	data, err := utils.ReadFile(torrentPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not edit torrent: %w", err)
+	}
+
+	e := &TorrentEditor{infoHash: infoHash}
+	err = bencode.Unmarshal(data, &e.dict)
+	if err != nil {
+		return nil, fmt.Errorf("could not edit torrent: %w", err)
+	}
+	e.dict.Set("info", bencode.RawMessage(torrent.rawInfo))
+
+	// Only the order of the outer keys matters, as the info dictionary is kept as is
+	e.canonical = true
+	for i := 1; i < len(e.dict); i++ {
+		if e.dict[i-1].Key >= e.dict[i].Key {
+			e.canonical = false
+		}
+	}
+	return e, nil
+}
+
+// Set sets the value of a key of the outer dictionary, which can be any value supported by bencode.Encode. The info
+// dictionary cannot be set.
+func (e *TorrentEditor) Set(key string, value interface{}) error {
+	if key == "info" {
+		return fmt.Errorf("could not edit torrent: info dictionary cannot be edited")
+	}
+	e.dict.Set(key, value)
+	return nil
+}
+
+// Delete removes a key from the outer dictionary. The info dictionary cannot be removed.
+func (e *TorrentEditor) Delete(key string) error {
+	if key == "info" {
+		return fmt.Errorf("could not edit torrent: info dictionary cannot be edited")
+	}
+	e.dict.Delete(key)
+	return nil
+}
+
+// SetTrackers replaces the trackers of the torrent with the given primary tracker URL and tiers of tracker URLs
+// (BEP 12). An empty URL or nil tiers remove the corresponding key.
+func (e *TorrentEditor) SetTrackers(announce string, announceList [][]string) {
+	e.setOrDelete("announce", announce, announce == "")
+	e.setOrDelete("announce-list", announceList, len(announceList) == 0)
+}
+
+// SetComment replaces the comment of the torrent, removing it if empty.
+func (e *TorrentEditor) SetComment(comment string) {
+	e.setOrDelete("comment", comment, comment == "")
+}
+
+// SetWebSeeds replaces the web seed URLs of the torrent (BEP 19), removing them if empty.
+func (e *TorrentEditor) SetWebSeeds(urls []string) {
+	e.setOrDelete("url-list", urls, len(urls) == 0)
+}
+
+// setOrDelete sets the value of a key of the outer dictionary, or removes the key if remove is set.
+func (e *TorrentEditor) setOrDelete(key string, value interface{}, remove bool) {
+	if remove {
+		e.dict.Delete(key)
+	} else {
+		e.dict.Set(key, value)
+	}
+}
+
+// Bytes returns the bencoded edited torrent, with the original bytes of its info dictionary. If the torrent file was
+// in canonical form, keys are kept sorted.
+func (e *TorrentEditor) Bytes() ([]byte, error) {
+	dict := e.dict
+	if e.canonical {
+		dict = slices.Clone(dict)
+		slices.SortStableFunc(dict, func(a, b bencode.DictEntry) int {
+			return strings.Compare(a.Key, b.Key)
+		})
+	}
+
+	data, err := bencode.Encode(dict)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode edited torrent: %w", err)
+	}
+	return data, nil
+}
+
+// Save writes the edited torrent to a .torrent file at the given path, which may be the edited one, and reports its
+// info hash before and after editing. The torrent is first written to a temporary file, parsed back and only moved to
+// the given path if its info hash did not change, so a failed save leaves the file untouched.
+func (e *TorrentEditor) Save(torrentPath string) (EditResult, error) {
+	data, err := e.Bytes()
+	if err != nil {
+		return EditResult{}, err
+	}
+
+	var result EditResult
+	err = writeVerified(torrentPath, data, func(tmpPath string) error {
+		var err error
+		result, err = e.verify(tmpPath)
+		return err
+	})
+	return result, err
+}
+
+// verify parses the edited torrent written at the given path and reports its info hash before and after editing,
+// failing if it changed.
+func (e *TorrentEditor) verify(torrentPath string) (EditResult, error) {
+	edited, err := TorrentFromFile(torrentPath)
+	if err != nil {
+		return EditResult{}, fmt.Errorf("could not verify edited torrent: %w", err)
+	}
+	infoHash, err := edited.InfoHash()
+	if err != nil {
+		return EditResult{}, fmt.Errorf("could not verify edited torrent: %w", err)
+	}
+	result := EditResult{InfoHashBefore: hex.EncodeToString(e.infoHash), InfoHashAfter: hex.EncodeToString(infoHash)}
+	if !bytes.Equal(infoHash, e.infoHash) {
+		return result, fmt.Errorf("could not verify edited torrent: info hash changed from %s to %s",
+			result.InfoHashBefore, result.InfoHashAfter)
+	}
+	return result, nil
+}
@@ -0,0 +1,101 @@
+package bittorrent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GFLdev/gorrent/pkg/bencode"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEditTorrent(t *testing.T) {
+	t.Parallel()
+	info := map[string]interface{}{
+		"name":         "file.bin",
+		"piece length": 16,
+		"length":       20,
+		"pieces":       strings.Repeat("a", 2*20),
+		"x-unknown":    "kept",
+	}
+	data, err := bencode.Marshal(map[string]interface{}{
+		"announce": "http://old.example.com/announce",
+		"comment":  "old",
+		"url-list": []interface{}{"http://seed.example.com/"},
+		"info":     info,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	path := filepath.Join(t.TempDir(), "test.torrent")
+	if !assert.NoError(t, os.WriteFile(path, data, 0o644)) {
+		return
+	}
+
+	editor, err := EditTorrent(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	editor.SetTrackers("http://new.example.com/announce", [][]string{{"http://new.example.com/announce"}})
+	editor.SetComment("new")
+	editor.SetWebSeeds(nil)
+	assert.NoError(t, editor.Set("created by", "gorrent"))
+	assert.Error(t, editor.Set("info", map[string]interface{}{}))
+	assert.Error(t, editor.Delete("info"))
+
+	result, err := editor.Save(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, result.InfoHashBefore, result.InfoHashAfter)
+
+	edited, err := os.ReadFile(path)
+	if assert.NoError(t, err) {
+		assert.True(t, bencode.IsCanonical(edited))
+		rawInfo, err := bencode.Get(edited, "info")
+		if assert.NoError(t, err) {
+			expected, _ := bencode.Marshal(info)
+			assert.Equal(t, expected, []byte(rawInfo))
+		}
+	}
+
+	torrent, err := TorrentFromFile(path)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "http://new.example.com/announce", torrent.Announce)
+		assert.Equal(t, [][]string{{"http://new.example.com/announce"}}, torrent.AnnounceList)
+		assert.Equal(t, "new", torrent.Comment)
+		assert.Equal(t, "gorrent", torrent.CreatedBy)
+		assert.Nil(t, torrent.URLList)
+	}
+}
+
+func TestEditTorrentNonCanonicalInfo(t *testing.T) {
+	t.Parallel()
+	// Sorted outer dictionary holding an unsorted info dictionary
+	info := "d4:name8:file.bin6:lengthi20e12:piece lengthi16e6:pieces40:" + strings.Repeat("a", 2*20) + "e"
+	path := filepath.Join(t.TempDir(), "test.torrent")
+	if !assert.NoError(t, os.WriteFile(path, []byte("d8:announce19:http://example.com/4:info"+info+"e"), 0o644)) {
+		return
+	}
+
+	editor, err := EditTorrent(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	editor.SetComment("new")
+	data, err := editor.Bytes()
+	if assert.NoError(t, err) {
+		assert.Equal(t, "d8:announce19:http://example.com/7:comment3:new4:info"+info+"e", string(data))
+	}
+
+	// A failed verification leaves the existing file untouched
+	before, _ := os.ReadFile(path)
+	editor.infoHash = make([]byte, 20)
+	_, err = editor.Save(path)
+	assert.ErrorContains(t, err, "info hash changed")
+	after, _ := os.ReadFile(path)
+	assert.Equal(t, before, after)
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	assert.Len(t, entries, 1)
+}
@@ -1,6 +1,9 @@
 package bittorrent
 
-import "fmt"
+import (
+	"bytes"
+	"fmt"
+)
 
 // TorrentProtocol represents the protocol identifier string used in BitTorrent communications.
 const TorrentProtocol = "BitTorrent protocol"
@@ -9,7 +12,7 @@ const TorrentProtocol = "BitTorrent protocol"
 type Handshake struct {
 	// Protocol specifies the protocol identifier.
 	Protocol string
-	// InfoHash contains the SHA-1 hash of the torrent's info dictionary.
+	// InfoHash contains the SHA-1 hash of the torrent's info dictionary, or its truncated v2 hash (BEP 52).
 	InfoHash [20]byte
 	// PeerID is a unique identifier for the peer.
 	PeerID [20]byte
@@ -59,3 +62,22 @@ func (h *Handshake) SerializeHandshake() []byte {
 	i += copy(buf[i:], h.PeerID[:])     // peer id
 	return buf
 }
+
+// VerifyHandshake checks that a handshake received from a peer uses the BitTorrent protocol and is for the torrent,
+// under any of its info hashes, and returns the matching one. Peers of hybrid torrents may use either their v1 or
+// their truncated v2 info hash.
+func (t *TorrentFile) VerifyHandshake(h *Handshake) ([]byte, error) {
+	if h.Protocol != TorrentProtocol {
+		return nil, fmt.Errorf("invalid handshake: unknown protocol '%s'", h.Protocol)
+	}
+	hashes, err := t.InfoHashes()
+	if err != nil {
+		return nil, fmt.Errorf("could not verify handshake: %w", err)
+	}
+	for _, hash := range hashes {
+		if bytes.Equal(h.InfoHash[:], hash) {
+			return hash, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid handshake: info hash %x does not match the torrent", h.InfoHash)
+}
@@ -0,0 +1,245 @@
+package bittorrent
+
+import (
+	"cmp"
+	"encoding/base32"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Prefixes of the exact topics ("xt" parameters) of magnet links.
+const (
+	// btihPrefix prefixes v1 info hashes, as 40 hexadecimal or 32 base32 characters.
+	btihPrefix = "urn:btih:"
+	// btmhPrefix prefixes v2 info hashes, as hexadecimal SHA-256 multihashes (BEP 52).
+	btmhPrefix = "urn:btmh:"
+	// sha256Multihash is the multihash header of a SHA-256 digest: function code 0x12 and length 0x20.
+	sha256Multihash = "1220"
+)
+
+// maxSelectOnly is the maximum number of file indexes selected by a magnet link.
+const maxSelectOnly = 1 << 16
+
+// Magnet represents a magnet link identifying a torrent by its info hash, as defined by BEP 9.
+type Magnet struct {
+	// InfoHash is the 20-byte SHA-1 v1 info hash, if present.
+	InfoHash []byte
+	// InfoHashV2 is the 32-byte SHA-256 v2 info hash (BEP 52), if present.
+	InfoHashV2 []byte
+	// DisplayName is the name to display while the metadata is fetched.
+	DisplayName string
+	// Trackers lists tracker URLs.
+	Trackers []string
+	// WebSeeds lists web seed URLs (BEP 19).
+	WebSeeds []string
+	// Peers lists peer addresses to connect to, as host:port.
+	Peers []string
+	// SelectOnly lists the indexes of the files to download (BEP 53), or nil to download all of them.
+	SelectOnly []int
+}
+
+// ParseMagnet parses a magnet URI. At least one exact topic must be given, as a v1 info hash ("urn:btih:", in hex or
+// base32) or a v2 info hash ("urn:btmh:"). Unknown parameters are ignored.
+func ParseMagnet(uri string) (*Magnet, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse magnet: %w", err)
+	}
+	if u.Scheme != "magnet" {
+		return nil, fmt.Errorf("could not parse magnet: invalid scheme '%s'", u.Scheme)
+	}
+	query, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse magnet: %w", err)
+	}
+
+	// Numbered parameters, e.g. "tr.1", are equivalent to unnumbered ones, and are read in the order of their numbers
+	keys := slices.Collect(maps.Keys(query))
+	slices.SortFunc(keys, func(a, b string) int {
+		nameA, numA := splitMagnetKey(a)
+		nameB, numB := splitMagnetKey(b)
+		if c := strings.Compare(nameA, nameB); c != 0 {
+			return c
+		}
+		return cmp.Compare(numA, numB)
+	})
+
+	m := &Magnet{}
+	for _, key := range keys {
+		values := query[key]
+		key, _ = splitMagnetKey(key)
+		for _, value := range values {
+			switch key {
+			case "xt":
+				err = m.parseExactTopic(value)
+			case "dn":
+				m.DisplayName = value
+			case "tr":
+				m.Trackers = append(m.Trackers, value)
+			case "ws":
+				m.WebSeeds = append(m.WebSeeds, value)
+			case "x.pe":
+				m.Peers = append(m.Peers, value)
+			case "so":
+				m.SelectOnly, err = parseSelectOnly(value)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("could not parse magnet: %w", err)
+			}
+		}
+	}
+
+	if m.InfoHash == nil && m.InfoHashV2 == nil {
+		return nil, fmt.Errorf("could not parse magnet: missing info hash")
+	}
+	return m, nil
+}
+
+// splitMagnetKey splits a numbered magnet parameter, e.g. "tr.1" or "x.pe.1", into its name and number, after the
+// last dot. Unnumbered parameters are returned as is, with number -1.
+func splitMagnetKey(key string) (name string, number int) {
+	if i := strings.LastIndexByte(key, '.'); i >= 0 {
+		if number, err := strconv.Atoi(key[i+1:]); err == nil && number >= 0 {
+			return key[:i], number
+		}
+	}
+	return key, -1
+}
+
+// parseExactTopic parses the value of an "xt" parameter, setting the info hash it holds. Topics of other kinds are
+// ignored.
+func (m *Magnet) parseExactTopic(topic string) error {
+	if hash, ok := strings.CutPrefix(topic, btihPrefix); ok {
+		var decoded []byte
+		var err error
+		switch len(hash) {
+		case 40:
+			decoded, err = hex.DecodeString(hash)
+		case 32:
+			decoded, err = base32.StdEncoding.DecodeString(strings.ToUpper(hash))
+		default:
+			err = fmt.Errorf("invalid length %d", len(hash))
+		}
+		if err != nil {
+			return fmt.Errorf("invalid v1 info hash '%s': %w", hash, err)
+		}
+		m.InfoHash = decoded
+	} else if hash, ok := strings.CutPrefix(topic, btmhPrefix); ok {
+		digest, ok := strings.CutPrefix(strings.ToLower(hash), sha256Multihash)
+		decoded, err := hex.DecodeString(digest)
+		if !ok || err != nil || len(decoded) != 32 {
+			return fmt.Errorf("invalid v2 info hash '%s': expected SHA-256 multihash", hash)
+		}
+		m.InfoHashV2 = decoded
+	}
+	return nil
+}
+
+// parseSelectOnly parses the value of a "so" parameter (BEP 53): comma-separated file indexes and inclusive ranges,
+// e.g. "0,2,4-6".
+func parseSelectOnly(value string) ([]int, error) {
+	var indexes []int
+	for _, item := range strings.Split(value, ",") {
+		first, last, isRange := strings.Cut(item, "-")
+		start, err := strconv.Atoi(first)
+		end := start
+		if err == nil && isRange {
+			end, err = strconv.Atoi(last)
+		}
+		if err != nil || start < 0 || end < start {
+			return nil, fmt.Errorf("invalid file selection '%s'", item)
+		}
+		if end-start >= maxSelectOnly-len(indexes) {
+			return nil, fmt.Errorf("file selection '%s' exceeds %d files", item, maxSelectOnly)
+		}
+		for i := start; i <= end; i++ {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes, nil
+}
+
+// formatSelectOnly formats file indexes as the value of a "so" parameter, merging consecutive indexes into ranges.
+func formatSelectOnly(indexes []int) string {
+	sorted := slices.Clone(indexes)
+	slices.Sort(sorted)
+	sorted = slices.Compact(sorted)
+
+	var items []string
+	for i := 0; i < len(sorted); {
+		j := i
+		for j+1 < len(sorted) && sorted[j+1] == sorted[j]+1 {
+			j++
+		}
+		if i == j {
+			items = append(items, strconv.Itoa(sorted[i]))
+		} else {
+			items = append(items, strconv.Itoa(sorted[i])+"-"+strconv.Itoa(sorted[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(items, ",")
+}
+
+// String returns the magnet URI, with info hashes in hexadecimal.
+func (m *Magnet) String() string {
+	var params []string
+	if m.InfoHash != nil {
+		params = append(params, "xt="+btihPrefix+hex.EncodeToString(m.InfoHash))
+	}
+	if m.InfoHashV2 != nil {
+		params = append(params, "xt="+btmhPrefix+sha256Multihash+hex.EncodeToString(m.InfoHashV2))
+	}
+	if m.DisplayName != "" {
+		params = append(params, "dn="+url.QueryEscape(m.DisplayName))
+	}
+	for _, tracker := range m.Trackers {
+		params = append(params, "tr="+url.QueryEscape(tracker))
+	}
+	for _, seed := range m.WebSeeds {
+		params = append(params, "ws="+url.QueryEscape(seed))
+	}
+	for _, peer := range m.Peers {
+		params = append(params, "x.pe="+url.QueryEscape(peer))
+	}
+	if len(m.SelectOnly) > 0 {
+		params = append(params, "so="+formatSelectOnly(m.SelectOnly))
+	}
+	return "magnet:?" + strings.Join(params, "&")
+}
+
+// Magnet returns a magnet link of the torrent, with its info hashes, name, trackers and web seeds. v2 torrents are
+// identified by their v2 info hash, and hybrid torrents by both hashes.
+func (t *TorrentFile) Magnet() (*Magnet, error) {
+	m := &Magnet{DisplayName: t.Info.Name, WebSeeds: t.URLList}
+	var err error
+	if t.Info.IsV1() {
+		m.InfoHash, err = t.InfoHash()
+		if err != nil {
+			return nil, fmt.Errorf("could not create magnet: %w", err)
+		}
+	}
+	if t.Info.IsV2() {
+		m.InfoHashV2, err = t.InfoHashV2()
+		if err != nil {
+			return nil, fmt.Errorf("could not create magnet: %w", err)
+		}
+	}
+
+	for _, tier := range t.AnnounceList {
+		for _, tracker := range tier {
+			if tracker != "" && !slices.Contains(m.Trackers, tracker) {
+				m.Trackers = append(m.Trackers, tracker)
+			}
+		}
+	}
+	if t.Announce != "" && !slices.Contains(m.Trackers, t.Announce) {
+		m.Trackers = append([]string{t.Announce}, m.Trackers...)
+	}
+	return m, nil
+}
@@ -0,0 +1,86 @@
+package bittorrent
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMagnet(t *testing.T) {
+	t.Parallel()
+	hash, _ := hex.DecodeString("c12fe1c06bba254a9dc9f519b335aa7c1367a88a")
+	hashV2, _ := hex.DecodeString("d8dd32ac93357c368556af3ac1d95c9d76bd0dff6fa9833ecdac3d53134efabb")
+
+	uris := []string{
+		"magnet:?xt=urn:btih:c12fe1c06bba254a9dc9f519b335aa7c1367a88a&dn=Some+Name&tr=http%3A%2F%2Fa.example.com%2F" +
+			"announce&tr.1=udp%3A%2F%2Fb.example.com%3A80&ws=http%3A%2F%2Fseed.example.com%2F&x.pe=10.0.0.1%3A6881" +
+			"&so=0,2,4-6&xt=urn:btmh:1220d8dd32ac93357c368556af3ac1d95c9d76bd0dff6fa9833ecdac3d53134efabb",
+		"magnet:?xt=urn:btih:YEX6DQDLXISUVHOJ6UM3GNNKPQJWPKEK&dn=Some%20Name&tr=http://a.example.com/announce" +
+			"&tr=udp://b.example.com:80&ws=http://seed.example.com/&x.pe=10.0.0.1:6881&so=6,5,4,2,0" +
+			"&xt=urn:btmh:1220D8DD32AC93357C368556AF3AC1D95C9D76BD0DFF6FA9833ECDAC3D53134EFABB",
+	}
+	for _, uri := range uris {
+		m, err := ParseMagnet(uri)
+		if !assert.NoError(t, err, uri) {
+			continue
+		}
+		assert.Equal(t, hash, m.InfoHash, uri)
+		assert.Equal(t, hashV2, m.InfoHashV2, uri)
+		assert.Equal(t, "Some Name", m.DisplayName, uri)
+		assert.ElementsMatch(t, []string{"http://a.example.com/announce", "udp://b.example.com:80"}, m.Trackers, uri)
+		assert.Equal(t, []string{"http://seed.example.com/"}, m.WebSeeds, uri)
+		assert.Equal(t, []string{"10.0.0.1:6881"}, m.Peers, uri)
+		assert.ElementsMatch(t, []int{0, 2, 4, 5, 6}, m.SelectOnly, uri)
+
+		reparsed, err := ParseMagnet(m.String())
+		if assert.NoError(t, err, uri) {
+			assert.Equal(t, m.InfoHash, reparsed.InfoHash, uri)
+			assert.Equal(t, m.InfoHashV2, reparsed.InfoHashV2, uri)
+			assert.Equal(t, m.Trackers, reparsed.Trackers, uri)
+			assert.Equal(t, []int{0, 2, 4, 5, 6}, reparsed.SelectOnly, uri)
+		}
+	}
+
+	for _, uri := range []string{
+		"http://example.com/?xt=urn:btih:c12fe1c06bba254a9dc9f519b335aa7c1367a88a",
+		"magnet:?dn=name",
+		"magnet:?xt=urn:btih:c12f",
+		"magnet:?xt=urn:btmh:1114d8dd32ac93357c368556af3ac1d95c9d",
+		"magnet:?xt=urn:btih:c12fe1c06bba254a9dc9f519b335aa7c1367a88a&so=3-1",
+		"magnet:?xt=urn:btih:c12fe1c06bba254a9dc9f519b335aa7c1367a88a&so=0-999999999",
+	} {
+		_, err := ParseMagnet(uri)
+		assert.Error(t, err, uri)
+	}
+}
+
+func TestParseMagnetNumberedOrder(t *testing.T) {
+	t.Parallel()
+	m, err := ParseMagnet("magnet:?xt=urn:btih:c12fe1c06bba254a9dc9f519b335aa7c1367a88a&tr.10=c&tr.2=b&tr=first" +
+		"&tr.1=a&ws.2=y&ws.1=x&x.pe.2=10.0.0.2:6881&x.pe.1=10.0.0.1:6881&x.pe=10.0.0.0:6881")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"first", "a", "b", "c"}, m.Trackers)
+		assert.Equal(t, []string{"x", "y"}, m.WebSeeds)
+		assert.Equal(t, []string{"10.0.0.0:6881", "10.0.0.1:6881", "10.0.0.2:6881"}, m.Peers)
+	}
+}
+
+func TestTorrentMagnet(t *testing.T) {
+	t.Parallel()
+	torrent := &TorrentFile{
+		Announce:     "http://a.example.com/announce",
+		AnnounceList: [][]string{{"http://a.example.com/announce", "http://b.example.com/announce"}},
+		URLList:      []string{"http://seed.example.com/"},
+		Info:         TorrentInfo{Name: "file name", PieceLength: 16, Length: 1, Pieces: "aaaaaaaaaaaaaaaaaaaa"},
+	}
+	hash, err := torrent.InfoHash()
+	assert.NoError(t, err)
+
+	m, err := torrent.Magnet()
+	if assert.NoError(t, err) {
+		assert.Equal(t, "magnet:?xt=urn:btih:"+hex.EncodeToString(hash)+"&dn=file+name"+
+			"&tr=http%3A%2F%2Fa.example.com%2Fannounce&tr=http%3A%2F%2Fb.example.com%2Fannounce"+
+			"&ws=http%3A%2F%2Fseed.example.com%2F", m.String())
+	}
+}
@@ -0,0 +1,123 @@
+package bittorrent
+
+import (
+	"fmt"
+	"sort"
+)
+
+// FileSegment represents a contiguous range of bytes of a file of the torrent.
+type FileSegment struct {
+	// File is the index of the file in the files of the torrent metadata.
+	File int
+	// Offset is the position of the first byte of the segment in the file.
+	Offset int64
+	// Length is the size of the segment in bytes.
+	Length int64
+}
+
+// PieceMap translates pieces of a torrent, and ranges of bytes of its content, into the ranges of the files they
+// cover, and back. Files are laid out at the offsets given by the metadata, each one starting where the previous one
+// ends, except in v2-only torrents, where each file starts at a piece boundary (BEP 52).
+type PieceMap struct {
+	// files holds the files of the torrent, in the order of their offsets.
+	files []FileMetadata
+	// pieceLength is the size of each piece in bytes, except possibly the last one.
+	pieceLength int64
+	// length is the size of the content in bytes, including the gaps between the files of v2-only torrents.
+	length int64
+}
+
+// NewPieceMap returns a map between the pieces and the files of a torrent, from its metadata. Files must not overlap
+// and must be given in the order of their offsets.
+func NewPieceMap(meta *TorrentMetadata) (*PieceMap, error) {
+	if meta.PieceLength <= 0 {
+		return nil, fmt.Errorf("could not map pieces: invalid piece length %d", meta.PieceLength)
+	}
+
+	m := &PieceMap{files: meta.Files, pieceLength: int64(meta.PieceLength)}
+	for i, file := range meta.Files {
+		if file.Length < 0 {
+			return nil, fmt.Errorf("could not map pieces: invalid length %d of file %d", file.Length, i)
+		}
+		if file.Offset < m.length {
+			return nil, fmt.Errorf("could not map pieces: invalid offset %d of file %d", file.Offset, i)
+		}
+		m.length = file.Offset + file.Length
+	}
+	return m, nil
+}
+
+// NumPieces returns the number of pieces of the torrent.
+func (m *PieceMap) NumPieces() int {
+	return int((m.length + m.pieceLength - 1) / m.pieceLength)
+}
+
+// PieceSize returns the size of the piece with the given index in bytes, which is the piece length for all the
+// pieces except possibly the last one, or 0 if there is no such piece.
+func (m *PieceMap) PieceSize(index int) int64 {
+	if index < 0 || index >= m.NumPieces() {
+		return 0
+	}
+	return min(m.pieceLength, m.length-int64(index)*m.pieceLength)
+}
+
+// Piece returns the file segments covered by the piece with the given index.
+func (m *PieceMap) Piece(index int) ([]FileSegment, error) {
+	if index < 0 || index >= m.NumPieces() {
+		return nil, fmt.Errorf("invalid piece index %d", index)
+	}
+	return m.Range(int64(index)*m.pieceLength, m.PieceSize(index))
+}
+
+// Block returns the file segments covered by length bytes of the piece with the given index, starting at begin.
+func (m *PieceMap) Block(index int, begin, length int64) ([]FileSegment, error) {
+	size := m.PieceSize(index)
+	if size == 0 {
+		return nil, fmt.Errorf("invalid piece index %d", index)
+	}
+	if begin < 0 || length < 0 || begin+length > size {
+		return nil, fmt.Errorf("invalid block of %d bytes at %d of piece %d", length, begin, index)
+	}
+	return m.Range(int64(index)*m.pieceLength+begin, length)
+}
+
+// Range returns the file segments covered by length bytes of the content, starting at offset, in order. Empty files
+// and the gaps between the files of v2-only torrents are not part of any segment, while padding files are, so they
+// can be told apart by the caller.
+func (m *PieceMap) Range(offset, length int64) ([]FileSegment, error) {
+	if offset < 0 || length < 0 || offset+length > m.length {
+		return nil, fmt.Errorf("invalid range of %d bytes at %d", length, offset)
+	}
+
+	end := offset + length
+	var segments []FileSegment
+	first := sort.Search(len(m.files), func(i int) bool {
+		return m.files[i].Offset+m.files[i].Length > offset
+	})
+	for i := first; i < len(m.files) && m.files[i].Offset < end; i++ {
+		start := max(offset, m.files[i].Offset)
+		stop := min(end, m.files[i].Offset+m.files[i].Length)
+		if stop > start {
+			segments = append(segments, FileSegment{File: i, Offset: start - m.files[i].Offset, Length: stop - start})
+		}
+	}
+	return segments, nil
+}
+
+// FilePieces returns the range of indexes of the pieces covering length bytes of the file with the given index,
+// starting at offset, from start included to end excluded. The range is empty if length is 0.
+func (m *PieceMap) FilePieces(file int, offset, length int64) (start, end int, err error) {
+	if file < 0 || file >= len(m.files) {
+		return 0, 0, fmt.Errorf("invalid file index %d", file)
+	}
+	if offset < 0 || length < 0 || offset+length > m.files[file].Length {
+		return 0, 0, fmt.Errorf("invalid range of %d bytes at %d of file %d", length, offset, file)
+	}
+
+	pos := m.files[file].Offset + offset
+	start = int(pos / m.pieceLength)
+	if length == 0 {
+		return start, start, nil
+	}
+	return start, int((pos + length + m.pieceLength - 1) / m.pieceLength), nil
+}
@@ -0,0 +1,95 @@
+package bittorrent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPieceMap(t *testing.T) {
+	t.Parallel()
+	m, err := NewPieceMap(&TorrentMetadata{
+		PieceLength: 16,
+		PieceHashes: make([]string, 3),
+		Files:       []FileMetadata{{Length: 10}, {Length: 0, Offset: 10}, {Length: 30, Offset: 10}},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 3, m.NumPieces())
+	assert.Equal(t, int64(16), m.PieceSize(0))
+	assert.Equal(t, int64(8), m.PieceSize(2))
+	assert.Equal(t, int64(0), m.PieceSize(3))
+
+	segments, err := m.Piece(0)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []FileSegment{{File: 0, Offset: 0, Length: 10}, {File: 2, Offset: 0, Length: 6}}, segments)
+	}
+	segments, err = m.Piece(2)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []FileSegment{{File: 2, Offset: 22, Length: 8}}, segments)
+	}
+	segments, err = m.Block(1, 4, 12)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []FileSegment{{File: 2, Offset: 10, Length: 12}}, segments)
+	}
+	segments, err = m.Range(5, 30)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []FileSegment{{File: 0, Offset: 5, Length: 5}, {File: 2, Offset: 0, Length: 25}}, segments)
+	}
+
+	start, end, err := m.FilePieces(2, 0, 30)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []int{0, 3}, []int{start, end})
+	}
+	start, end, err = m.FilePieces(2, 6, 16)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []int{1, 2}, []int{start, end})
+	}
+	start, end, err = m.FilePieces(1, 0, 0)
+	if assert.NoError(t, err) {
+		assert.Equal(t, start, end)
+	}
+
+	_, err = m.Piece(3)
+	assert.Error(t, err)
+	_, err = m.Block(2, 4, 5)
+	assert.Error(t, err)
+	_, err = m.Range(30, 11)
+	assert.Error(t, err)
+	_, _, err = m.FilePieces(0, 5, 6)
+	assert.Error(t, err)
+	_, err = NewPieceMap(&TorrentMetadata{})
+	assert.Error(t, err)
+	_, err = NewPieceMap(&TorrentMetadata{PieceLength: 16, Files: []FileMetadata{{Length: 10}, {Length: 5, Offset: 5}}})
+	assert.Error(t, err)
+}
+
+func TestPieceMapV2(t *testing.T) {
+	t.Parallel()
+	// Files of v2-only torrents start at piece boundaries
+	m, err := NewPieceMap(&TorrentMetadata{
+		MetaVersion: 2,
+		PieceLength: 16,
+		Files: []FileMetadata{
+			{Length: 10}, {Length: 20, Offset: 16}, {Length: 0, Offset: 36}, {Length: 5, Offset: 48},
+		},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 4, m.NumPieces())
+
+	segments, err := m.Piece(0)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []FileSegment{{File: 0, Offset: 0, Length: 10}}, segments)
+	}
+	segments, err = m.Piece(2)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []FileSegment{{File: 1, Offset: 16, Length: 4}}, segments)
+	}
+	start, end, err := m.FilePieces(3, 0, 5)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []int{3, 4}, []int{start, end})
+	}
+}
@@ -1,36 +1,182 @@
 package bittorrent
 
 import (
+	"bytes"
 	"encoding/hex"
 	"fmt"
 	"github.com/GFLdev/gorrent/pkg/bencode"
 	"github.com/GFLdev/gorrent/pkg/utils"
+	"net"
+	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // TorrentFile represents the metadata structure of a .torrent file parsed according to the BitTorrent specification.
 type TorrentFile struct {
 	// Announce specifies the primary tracker URL for the torrent.
-	Announce string `bencode:"announce"`
+	Announce string `bencode:"announce,omitempty"`
+	// AnnounceList holds tiers of tracker URLs (BEP 12), which take precedence over Announce when present.
+	AnnounceList [][]string `bencode:"announce-list,omitempty"`
 	// CreationDate represents the timestamp of when the torrent was created.
-	CreationDate int `bencode:"creation date"`
+	CreationDate time.Time `bencode:"creation date,omitempty"`
 	// Comment holds an optional textual description.
-	Comment string `bencode:"comment"`
+	Comment string `bencode:"comment,omitempty"`
 	// CreatedBy specifies the name and version of the application used to create the torrent file.
-	CreatedBy string `bencode:"created by"`
-	// URLList specifies an optional list of tracker URLs.
-	URLList []string `bencode:"url-list"`
+	CreatedBy string `bencode:"created by,omitempty"`
+	// Encoding specifies the character encoding of the strings of the torrent, usually UTF-8.
+	Encoding string `bencode:"encoding,omitempty"`
+	// URLList specifies an optional list of web seed URLs (BEP 19).
+	URLList SeedURLs `bencode:"url-list,omitempty"`
+	// HTTPSeeds specifies an optional list of HTTP seed URLs (BEP 17).
+	HTTPSeeds SeedURLs `bencode:"httpseeds,omitempty"`
+	// Nodes lists DHT nodes to bootstrap from, mostly found in trackerless torrents.
+	Nodes []Node `bencode:"nodes,omitempty"`
 	// Info represents the bencoded "info" dictionary containing essential metadata for the torrent.
-	Info struct {
-		// Pieces is a concatenated string of SHA-1 hashes.
-		Pieces string `bencode:"pieces"`
-		// PieceLength specifies the size of each piece in bytes.
-		PieceLength int `bencode:"piece length"`
-		// Length represents the total size of the file in bytes.
-		Length int `bencode:"length"`
-		// Name specifies the name of the primary file or the directory name.
-		Name string `bencode:"name"`
-	} `bencode:"info"`
+	Info TorrentInfo `bencode:"info,required"`
+	// PieceLayers maps the pieces roots of the files of v2 torrents longer than a piece to their piece layers, the
+	// concatenated SHA-256 hashes of their pieces (BEP 52).
+	PieceLayers map[string]string `bencode:"piece layers,omitempty"`
+	// rawInfo holds the exact bencoded bytes of the info dictionary, when parsed from a file.
+	rawInfo bencode.RawMessage
+	// tiers holds the tiers of tracker URLs in the order they are tried, initialized by TrackerTiers.
+	tiers [][]string
+}
+
+// TorrentInfo represents the "info" dictionary of a torrent, describing its content. Single-file torrents set Length
+// and name the file with Name, while multi-file torrents list their files in Files, under the directory named Name.
+// v2 torrents (BEP 52) describe their files with FileTree instead, and hybrid torrents hold both descriptions.
+type TorrentInfo struct {
+	// Pieces is a concatenated string of SHA-1 hashes, absent in v2-only torrents.
+	Pieces string `bencode:"pieces,omitempty"`
+	// PieceLength specifies the size of each piece in bytes.
+	PieceLength int `bencode:"piece length,required"`
+	// Length represents the size of the file in bytes, in single-file torrents.
+	Length int64 `bencode:"length,omitempty"`
+	// Name specifies the name of the file, or of the directory holding the files in multi-file torrents.
+	Name string `bencode:"name,required"`
+	// Files lists the files of multi-file torrents, in the order their content is laid out in pieces.
+	Files []FileInfo `bencode:"files,omitempty"`
+	// Private restricts peers to those returned by the trackers of the torrent (BEP 27).
+	Private bool `bencode:"private,omitempty"`
+	// Source identifies the origin of the torrent, usually a private tracker, making its info hash unique to it.
+	Source string `bencode:"source,omitempty"`
+	// MetaVersion is the version of the metainfo format, 2 for v2 and hybrid torrents (BEP 52).
+	MetaVersion int `bencode:"meta version,omitempty"`
+	// FileTree describes the files of v2 torrents, under the directory named Name, or as a single file named Name.
+	FileTree *FileTree `bencode:"file tree,omitempty"`
+	// Attr holds the attributes of the file of single-file torrents (BEP 47).
+	Attr FileAttr `bencode:"attr,omitempty"`
+	// SymlinkPath holds the path components of the target of the file of single-file torrents, if it is a symlink.
+	SymlinkPath []string `bencode:"symlink path,omitempty"`
+}
+
+// FileInfo represents a file entry in the "files" list of a multi-file torrent.
+type FileInfo struct {
+	// Length represents the size of the file in bytes.
+	Length int64 `bencode:"length"`
+	// Path holds the path components of the file, relative to the torrent directory, the last being the file name.
+	Path []string `bencode:"path,required"`
+	// Attr holds the attributes of the file (BEP 47).
+	Attr FileAttr `bencode:"attr,omitempty"`
+	// SymlinkPath holds the path components of the target of the file, relative to the torrent directory, if it is a
+	// symlink.
+	SymlinkPath []string `bencode:"symlink path,omitempty"`
+}
+
+// Node represents a DHT node of the "nodes" list of a torrent, bencoded as a list of its host and port.
+type Node struct {
+	// Host is the hostname or IP address of the node.
+	Host string
+	// Port is the UDP port of the node.
+	Port uint16
+}
+
+// MarshalBencode encodes the node as a list of its host and port.
+func (n Node) MarshalBencode() ([]byte, error) {
+	return bencode.Encode([]interface{}{n.Host, n.Port})
+}
+
+// UnmarshalBencode decodes a node from a list of its host and port.
+func (n *Node) UnmarshalBencode(data []byte) error {
+	var pair []interface{}
+	err := bencode.Unmarshal(data, &pair)
+	if err != nil {
+		return fmt.Errorf("could not parse node: %w", err)
+	}
+	if len(pair) != 2 {
+		return fmt.Errorf("could not parse node: expected host and port, got %d values", len(pair))
+	}
+
+	host, ok := pair[0].(string)
+	if !ok || host == "" {
+		return fmt.Errorf("could not parse node: invalid host")
+	}
+	port, ok := pair[1].(int)
+	if !ok || port <= 0 || port > 65535 {
+		return fmt.Errorf("could not parse node: invalid port")
+	}
+	n.Host, n.Port = host, uint16(port)
+	return nil
+}
+
+// String returns the address of the node, as host:port.
+func (n Node) String() string {
+	return net.JoinHostPort(n.Host, strconv.Itoa(int(n.Port)))
+}
+
+// SeedURLs represents a list of seed URLs, which torrents may also hold as a single string, as allowed by BEP 19. It
+// is always encoded as a list.
+type SeedURLs []string
+
+// UnmarshalBencode decodes seed URLs from a list of strings, or from a single string, empty strings being ignored.
+func (s *SeedURLs) UnmarshalBencode(data []byte) error {
+	if len(data) > 0 && data[0] >= '0' && data[0] <= '9' {
+		var url string
+		err := bencode.Unmarshal(data, &url)
+		if err != nil {
+			return fmt.Errorf("could not parse seed url: %w", err)
+		}
+		*s = nil
+		if url != "" {
+			*s = SeedURLs{url}
+		}
+		return nil
+	}
+
+	var urls []string
+	err := bencode.Unmarshal(data, &urls)
+	if err != nil {
+		return fmt.Errorf("could not parse seed urls: %w", err)
+	}
+	*s = urls
+	return nil
+}
+
+// rawTorrentFile decodes a torrent file in a single pass, keeping the exact bencoded bytes of its info dictionary.
+type rawTorrentFile struct {
+	// TorrentFile receives the decoded torrent file, except its info dictionary.
+	*TorrentFile
+	// Info decodes the info dictionary into the torrent file, taking precedence over its own Info field.
+	Info rawInfoDict `bencode:"info,required"`
+}
+
+// rawInfoDict decodes an info dictionary into the Info field of a torrent file, and keeps its bytes in rawInfo.
+type rawInfoDict struct {
+	// torrent is the torrent file receiving the info dictionary.
+	torrent *TorrentFile
+}
+
+// UnmarshalBencode decodes the info dictionary into the torrent file, keeping a copy of its bytes.
+func (d *rawInfoDict) UnmarshalBencode(data []byte) error {
+	err := bencode.Unmarshal(data, &d.torrent.Info)
+	if err != nil {
+		return err
+	}
+	d.torrent.rawInfo = bytes.Clone(data)
+	return nil
 }
 
 // TorrentMetadata represents metadata information parsed from a torrent file.
@@ -39,14 +185,67 @@ type TorrentMetadata struct {
 	Name string
 	// TrackerURL is the URL of the tracker used to announce peers.
 	TrackerURL string
-	// Length is the total size of the torrent content in bytes.
-	Length int
-	// InfoHash is a SHA-1 hash of the torrent's info dictionary, used to uniquely identify the torrent.
+	// AnnounceList holds the tiers of tracker URLs (BEP 12), as listed in the torrent file.
+	AnnounceList [][]string
+	// Length is the total size of the torrent content in bytes, across all files, excluding padding files (BEP 47).
+	Length int64
+	// InfoHash is a SHA-1 hash of the torrent's info dictionary, used to uniquely identify the torrent. For v2-only
+	// torrents, it is the v2 info hash truncated to 20 bytes.
 	InfoHash string
+	// InfoHashV2 is the SHA-256 hash of the torrent's info dictionary for v2 and hybrid torrents (BEP 52), or empty
+	// otherwise. Hybrid torrents are identified by both InfoHash and InfoHashV2 truncated to 20 bytes.
+	InfoHashV2 string
+	// MetaVersion is the version of the metainfo format: 1, or 2 for v2 and hybrid torrents.
+	MetaVersion int
 	// PieceLength is the size of each piece in bytes, except possibly the last one.
 	PieceLength int
-	// PieceHashes contains a list of SHA-1 hashes for each piece of the torrent content.
+	// PieceHashes contains a list of SHA-1 hashes for each piece of the torrent content, empty for v2-only torrents.
 	PieceHashes []string
+	// Files lists the files of the torrent, in the order their content is laid out in pieces. Single-file torrents
+	// hold a single file.
+	Files []FileMetadata
+	// CreationDate is the time the torrent was created, or the zero time if unknown.
+	CreationDate time.Time
+	// Comment is an optional textual description.
+	Comment string
+	// CreatedBy is the name and version of the application used to create the torrent file.
+	CreatedBy string
+	// Encoding is the character encoding of the strings of the torrent, if specified.
+	Encoding string
+	// Private tells whether peers must only be obtained from the trackers of the torrent (BEP 27).
+	Private bool
+	// Source identifies the origin of the torrent, if specified.
+	Source string
+	// WebSeeds lists the web seed URLs (BEP 19).
+	WebSeeds []string
+	// HTTPSeeds lists the HTTP seed URLs (BEP 17).
+	HTTPSeeds []string
+	// Nodes lists the DHT nodes to bootstrap from. It is empty for private torrents, which must not use the DHT.
+	Nodes []Node
+}
+
+// FileMetadata represents a file of the torrent content.
+type FileMetadata struct {
+	// Path is the path of the file relative to the download directory, starting with the torrent directory in
+	// multi-file torrents.
+	Path string
+	// Length is the size of the file in bytes.
+	Length int64
+	// Offset is the position of the first byte of the file in the torrent content, as if all files were concatenated,
+	// or at the next piece boundary in v2-only torrents (BEP 52).
+	Offset int64
+	// PiecesRoot is the SHA-256 root of the Merkle tree of the file content in v2 torrents, or empty otherwise.
+	PiecesRoot string
+	// Padding tells whether the file is a padding file (BEP 47), which is not part of the torrent content and must not
+	// be written to disk.
+	Padding bool
+	// Executable tells whether the file should be made executable (BEP 47).
+	Executable bool
+	// Hidden tells whether the file should be hidden (BEP 47).
+	Hidden bool
+	// SymlinkPath is the path of the target of the file relative to the download directory, like Path, if the file is
+	// a symlink (BEP 47), or empty otherwise.
+	SymlinkPath string
 }
 
 // TorrentFromFile reads a torrent file from the given path, parses its content, and returns a TorrentFile instance.
@@ -57,17 +256,27 @@ func TorrentFromFile(torrentPath string) (*TorrentFile, error) {
 	}
 	torrent := &TorrentFile{}
 
-	err = bencode.Unmarshal(torrentFile, torrent)
+	// Keep original info dictionary, as re-encoding it would drop undeclared keys
+	err = bencode.Unmarshal(torrentFile, &rawTorrentFile{TorrentFile: torrent, Info: rawInfoDict{torrent: torrent}})
 	if err != nil {
 		return nil, fmt.Errorf("could not parse torrent file: %s\n", err.Error())
 	}
 	return torrent, nil
 }
 
-// InfoHash generates and returns the SHA-1 hash of the bencoded info dictionary of the torrent.
+// InfoHash generates and returns the SHA-1 hash of the bencoded info dictionary of the torrent, or the v2 info hash
+// truncated to 20 bytes for v2-only torrents, as used by trackers and peers. For torrents parsed from a file, the hash
+// is computed over the exact bytes of the info dictionary found in the file.
 func (t *TorrentFile) InfoHash() ([]byte, error) {
-	// Bencode info dictionary
-	infoBCode, err := bencode.Marshal(&t.Info)
+	if !t.Info.IsV1() {
+		hash, err := t.InfoHashV2()
+		if err != nil {
+			return nil, fmt.Errorf("could not calculate info hash: %w", err)
+		}
+		return hash[:20], nil
+	}
+
+	infoBCode, err := t.infoBytes()
 	if err != nil {
 		return nil, fmt.Errorf("could not calculate info hash: %w", err)
 	}
@@ -76,12 +285,300 @@ func (t *TorrentFile) InfoHash() ([]byte, error) {
 	return utils.SHA1Encode(infoBCode), nil
 }
 
+// InfoHashes returns the 20-byte info hashes identifying the torrent to trackers and peers: the v1 info hash and, for
+// hybrid torrents, the v2 info hash truncated to 20 bytes as well.
+func (t *TorrentFile) InfoHashes() ([][]byte, error) {
+	hash, err := t.InfoHash()
+	if err != nil {
+		return nil, err
+	}
+	hashes := [][]byte{hash}
+	if t.Info.IsV1() && t.Info.IsV2() {
+		hashV2, err := t.InfoHashV2()
+		if err != nil {
+			return nil, fmt.Errorf("could not calculate info hash: %w", err)
+		}
+		hashes = append(hashes, hashV2[:20])
+	}
+	return hashes, nil
+}
+
+// infoBytes returns the bencoded info dictionary: its exact bytes for torrents parsed from a file, or its encoding
+// otherwise.
+func (t *TorrentFile) infoBytes() ([]byte, error) {
+	if len(t.rawInfo) > 0 {
+		return t.rawInfo, nil
+	}
+	return bencode.Marshal(&t.Info)
+}
+
+// PeerSource identifies a way of discovering peers of a torrent.
+type PeerSource int
+
+// Sources of peers.
+const (
+	// PeerSourceTracker obtains peers from the trackers of the torrent.
+	PeerSourceTracker PeerSource = iota
+	// PeerSourceDHT obtains peers from the distributed hash table (BEP 5).
+	PeerSourceDHT
+	// PeerSourcePEX obtains peers from other peers, by peer exchange (BEP 11).
+	PeerSourcePEX
+	// PeerSourceLSD obtains peers from the local network, by local service discovery (BEP 14).
+	PeerSourceLSD
+)
+
+// AllowsPeerSource reports whether peers of the torrent may be obtained from the given source. Private torrents
+// only allow their trackers (BEP 27).
+func (t *TorrentFile) AllowsPeerSource(source PeerSource) bool {
+	return source == PeerSourceTracker || !t.Info.Private
+}
+
+// DHTNodes returns the DHT nodes to bootstrap from, or nil if the torrent does not allow the DHT as peer source.
+func (t *TorrentFile) DHTNodes() []Node {
+	if !t.AllowsPeerSource(PeerSourceDHT) {
+		return nil
+	}
+	return t.Nodes
+}
+
+// IsMultiFile reports whether the torrent holds a directory of files, listed in Files, instead of a single file.
+func (info *TorrentInfo) IsMultiFile() bool {
+	return info.Files != nil
+}
+
+// TotalLength returns the total size of the torrent content in bytes, across all files. Padding files (BEP 47) are
+// not part of the content, so they are not counted.
+func (info *TorrentInfo) TotalLength() int64 {
+	var total int64
+	if !info.IsV1() {
+		if info.FileTree != nil {
+			for _, file := range info.FileTree.Files() {
+				total += file.Length
+			}
+		}
+		return total
+	}
+	if !info.IsMultiFile() {
+		return info.Length
+	}
+
+	for _, file := range info.Files {
+		if !file.Attr.IsPadding() {
+			total += file.Length
+		}
+	}
+	return total
+}
+
+// piecesLength returns the size in bytes of the data hashed by the v1 pieces of the torrent, which includes padding
+// files.
+func (info *TorrentInfo) piecesLength() int64 {
+	if !info.IsMultiFile() {
+		return info.Length
+	}
+
+	var total int64
+	for _, file := range info.Files {
+		total += file.Length
+	}
+	return total
+}
+
+// FilesMetadata returns the files of the torrent, with their paths and offsets in the torrent content. Paths are
+// checked to be relative and to stay inside the torrent directory. Hybrid torrents list their v1 files, padding files
+// included, which are checked to match the v2 file tree.
+func (info *TorrentInfo) FilesMetadata() ([]FileMetadata, error) {
+	if !validPathComponent(info.Name) {
+		return nil, fmt.Errorf("invalid torrent name '%s'", info.Name)
+	}
+	if !info.IsV1() {
+		return info.treeFilesMetadata()
+	}
+	if info.IsV2() {
+		if err := info.checkHybrid(); err != nil {
+			return nil, err
+		}
+	}
+	if !info.IsMultiFile() {
+		if info.Length < 0 {
+			return nil, fmt.Errorf("invalid file length %d", info.Length)
+		}
+		file := FileMetadata{Path: info.Name, Length: info.Length}
+		if info.IsV2() {
+			file.PiecesRoot = info.FileTree.Files()[0].PiecesRoot
+		}
+		if err := file.setAttributes(info.Attr, info.SymlinkPath, ""); err != nil {
+			return nil, fmt.Errorf("invalid attributes of file: %w", err)
+		}
+		return []FileMetadata{file}, nil
+	}
+
+	var treeFiles []TreeFile
+	if info.IsV2() {
+		treeFiles = info.FileTree.Files()
+	}
+
+	var offset int64
+	files := make([]FileMetadata, len(info.Files))
+	for i, file := range info.Files {
+		if file.Length < 0 {
+			return nil, fmt.Errorf("invalid length %d of file %d", file.Length, i)
+		}
+		if len(file.Path) == 0 {
+			return nil, fmt.Errorf("empty path of file %d", i)
+		}
+		for _, component := range file.Path {
+			if !validPathComponent(component) {
+				return nil, fmt.Errorf("invalid path component '%s' of file %d", component, i)
+			}
+		}
+
+		files[i] = FileMetadata{
+			Path:   filepath.Join(append([]string{info.Name}, file.Path...)...),
+			Length: file.Length,
+			Offset: offset,
+		}
+		if err := files[i].setAttributes(file.Attr, file.SymlinkPath, info.Name); err != nil {
+			return nil, fmt.Errorf("invalid attributes of file %d: %w", i, err)
+		}
+		if !file.Attr.IsPadding() && len(treeFiles) > 0 {
+			files[i].PiecesRoot = treeFiles[0].PiecesRoot
+			treeFiles = treeFiles[1:]
+		}
+		offset += file.Length
+	}
+	return files, nil
+}
+
+// checkHybrid checks that the v1 files of a hybrid torrent match the files of its v2 file tree, in the same order,
+// and that padding files align each of them to a piece boundary, so v1 and v2 pieces cover the same data.
+func (info *TorrentInfo) checkHybrid() error {
+	if info.FileTree == nil {
+		return fmt.Errorf("missing file tree")
+	}
+	if info.PieceLength <= 0 {
+		return fmt.Errorf("invalid piece length %d", info.PieceLength)
+	}
+	treeFiles := info.FileTree.Files()
+	if !info.IsMultiFile() {
+		if len(treeFiles) != 1 || !slices.Equal(treeFiles[0].Path, []string{info.Name}) ||
+			treeFiles[0].Length != info.Length {
+			return fmt.Errorf("file does not match the file tree")
+		}
+		return nil
+	}
+
+	var offset int64
+	next := 0
+	for i, file := range info.Files {
+		if file.Attr.IsPadding() {
+			offset += file.Length
+			continue
+		}
+		if next == len(treeFiles) || !slices.Equal(file.Path, treeFiles[next].Path) ||
+			file.Length != treeFiles[next].Length {
+			return fmt.Errorf("file %d does not match the file tree", i)
+		}
+		if file.Length > 0 && offset%int64(info.PieceLength) != 0 {
+			return fmt.Errorf("file %d is not aligned to a piece boundary", i)
+		}
+		offset += file.Length
+		next++
+	}
+	if next != len(treeFiles) {
+		return fmt.Errorf("%d files of the file tree are missing", len(treeFiles)-next)
+	}
+	return nil
+}
+
+// treeFilesMetadata returns the files of the file tree of a v2-only torrent, each non-empty file starting at a piece
+// boundary. A tree holding a single file named as the torrent describes a single-file torrent.
+func (info *TorrentInfo) treeFilesMetadata() ([]FileMetadata, error) {
+	if info.FileTree == nil {
+		return nil, fmt.Errorf("missing file tree")
+	}
+	if info.PieceLength <= 0 {
+		return nil, fmt.Errorf("invalid piece length %d", info.PieceLength)
+	}
+	treeFiles := info.FileTree.Files()
+	if len(treeFiles) == 0 {
+		return nil, fmt.Errorf("empty file tree")
+	}
+
+	var offset int64
+	pieceLength := int64(info.PieceLength)
+	files := make([]FileMetadata, len(treeFiles))
+	for i, file := range treeFiles {
+		if file.Length < 0 {
+			return nil, fmt.Errorf("invalid length %d of file %d", file.Length, i)
+		}
+		for _, component := range file.Path {
+			if !validPathComponent(component) {
+				return nil, fmt.Errorf("invalid path component '%s' of file %d", component, i)
+			}
+		}
+
+		if file.Length > 0 {
+			offset = (offset + pieceLength - 1) / pieceLength * pieceLength
+		}
+		root := info.Name
+		if len(treeFiles) == 1 && len(file.Path) == 1 && file.Path[0] == info.Name {
+			root = ""
+		}
+		files[i] = FileMetadata{
+			Path:       filepath.Join(append([]string{root}, file.Path...)...),
+			Length:     file.Length,
+			Offset:     offset,
+			PiecesRoot: file.PiecesRoot,
+		}
+		if err := files[i].setAttributes(file.Attr, file.SymlinkPath, root); err != nil {
+			return nil, fmt.Errorf("invalid attributes of file %d: %w", i, err)
+		}
+		offset += file.Length
+	}
+	return files, nil
+}
+
+// validPathComponent reports whether a file or directory name is safe to be joined to a path: not empty, not
+// referencing the current or parent directories and without separators.
+func validPathComponent(name string) bool {
+	return name != "" && name != "." && name != ".." && filepath.Base(name) == name && !filepath.IsAbs(name)
+}
+
 // GetMetadata extracts and returns metadata about a torrent.
 func (t *TorrentFile) GetMetadata() (TorrentMetadata, error) {
 	// Check valid pieces
 	if len(t.Info.Pieces)%20 != 0 {
 		return TorrentMetadata{}, fmt.Errorf("could not get torrent info: invalid pieces")
 	}
+	if t.Info.PieceLength <= 0 {
+		return TorrentMetadata{}, fmt.Errorf("could not get torrent info: invalid piece length")
+	}
+
+	// Get files and check that pieces cover all of them
+	files, err := t.Info.FilesMetadata()
+	if err != nil {
+		return TorrentMetadata{}, fmt.Errorf("could not get torrent info: %w", err)
+	}
+	pieceLength := int64(t.Info.PieceLength)
+	piecesLength := t.Info.piecesLength()
+	if t.Info.IsV1() && int64(len(t.Info.Pieces)/20) != (piecesLength+pieceLength-1)/pieceLength {
+		return TorrentMetadata{}, fmt.Errorf("could not get torrent info: number of pieces does not match length")
+	}
+
+	// Check the piece layers of v2 torrents and calculate their info hash
+	metaVersion := 1
+	var infoHashV2 []byte
+	if t.Info.IsV2() {
+		metaVersion = 2
+		if err = t.VerifyPieceLayers(); err != nil {
+			return TorrentMetadata{}, fmt.Errorf("could not get torrent info: %w", err)
+		}
+		infoHashV2, err = t.InfoHashV2()
+		if err != nil {
+			return TorrentMetadata{}, fmt.Errorf("could not get torrent info: %w", err)
+		}
+	}
 
 	idx := 0
 	pieceHashes := make([]string, len(t.Info.Pieces)/20)
@@ -98,23 +595,53 @@ func (t *TorrentFile) GetMetadata() (TorrentMetadata, error) {
 
 	// Struct info
 	torrentInfo := TorrentMetadata{
-		Name:        t.Info.Name,
-		TrackerURL:  t.Announce,
-		Length:      t.Info.Length,
-		InfoHash:    hex.EncodeToString(infoHash),
-		PieceLength: t.Info.PieceLength,
-		PieceHashes: pieceHashes,
+		Name:         t.Info.Name,
+		TrackerURL:   t.Announce,
+		AnnounceList: t.AnnounceList,
+		Length:       t.Info.TotalLength(),
+		InfoHash:     hex.EncodeToString(infoHash),
+		InfoHashV2:   hex.EncodeToString(infoHashV2),
+		MetaVersion:  metaVersion,
+		PieceLength:  t.Info.PieceLength,
+		PieceHashes:  pieceHashes,
+		Files:        files,
+		CreationDate: t.CreationDate,
+		Comment:      t.Comment,
+		CreatedBy:    t.CreatedBy,
+		Encoding:     t.Encoding,
+		Private:      t.Info.Private,
+		Source:       t.Info.Source,
+		WebSeeds:     t.URLList,
+		HTTPSeeds:    t.HTTPSeeds,
+		Nodes:        t.DHTNodes(),
 	}
 	return torrentInfo, nil
 }
 
+// formatTiers formats tiers of tracker URLs, separating trackers by commas and tiers by bars.
+func formatTiers(tiers [][]string) string {
+	if len(tiers) == 0 {
+		return "(empty)"
+	}
+	formatted := make([]string, len(tiers))
+	for i, tier := range tiers {
+		formatted[i] = strings.Join(tier, ", ")
+	}
+	return strings.Join(formatted, " | ")
+}
+
 // String returns a formatted string representation of the TorrentMetadata, including metadata and piece hash details.
 func (meta *TorrentMetadata) String() string {
 	infoStr := "Name: " + meta.Name + "\n" +
 		"Tracker URL: " + meta.TrackerURL + "\n" +
-		"Length: " + strconv.Itoa(meta.Length) + "\n" +
-		"Info Hash: " + meta.InfoHash + "\n" +
-		"Piece Length: " + strconv.Itoa(meta.PieceLength) + "\n" +
+		"Announce List: " + formatTiers(meta.AnnounceList) + "\n" +
+		"Length: " + strconv.FormatInt(meta.Length, 10) + "\n" +
+		"Info Hash: " + meta.InfoHash + "\n"
+	if meta.InfoHashV2 != "" {
+		infoStr += "Info Hash v2: " + meta.InfoHashV2 + "\n"
+	}
+	infoStr += "Piece Length: " + strconv.Itoa(meta.PieceLength) + "\n" +
+		"Private: " + strconv.FormatBool(meta.Private) + "\n" +
 		"Piece Hashes:"
 
 	if len(meta.PieceHashes) == 0 {
@@ -131,5 +658,14 @@ func (meta *TorrentMetadata) String() string {
 			infoStr += "\n" + utils.Base16ToHex(hash)
 		}
 	}
+
+	infoStr += "\nFiles:"
+	for n, file := range meta.Files {
+		if n == 5 { // print 5 files at maximum
+			infoStr += "\n... (" + strconv.Itoa(len(meta.Files)-n) + " more)"
+			break
+		}
+		infoStr += "\n" + file.Path + " (" + strconv.FormatInt(file.Length, 10) + " bytes)"
+	}
 	return infoStr
 }
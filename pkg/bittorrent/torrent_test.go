@@ -0,0 +1,198 @@
+package bittorrent
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GFLdev/gorrent/pkg/bencode"
+	"github.com/stretchr/testify/assert"
+)
+
+// SimNumbers is the number of simulations given to the tests of every package by the run_tests scripts. The tests of
+// this package do not run simulations, but must accept the flag.
+var SimNumbers = flag.Int("sim", 1000, "number of simulations to run")
+
+// writeTestTorrent bencodes a torrent dictionary into a temporary file and parses it with TorrentFromFile.
+func writeTestTorrent(t *testing.T, torrent map[string]interface{}) *TorrentFile {
+	t.Helper()
+	data, err := bencode.Marshal(torrent)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	path := filepath.Join(t.TempDir(), "test.torrent")
+	if !assert.NoError(t, os.WriteFile(path, data, 0o644)) {
+		t.FailNow()
+	}
+
+	parsed, err := TorrentFromFile(path)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return parsed
+}
+
+func TestMultiFileMetadata(t *testing.T) {
+	t.Parallel()
+	torrent := writeTestTorrent(t, map[string]interface{}{
+		"announce": "http://tracker.example.com/announce",
+		"info": map[string]interface{}{
+			"name":         "album",
+			"piece length": 16,
+			"pieces":       strings.Repeat("a", 3*20),
+			"files": []interface{}{
+				map[string]interface{}{"length": 10, "path": []interface{}{"cd1", "track1.flac"}},
+				map[string]interface{}{"length": 0, "path": []interface{}{"empty.txt"}},
+				map[string]interface{}{"length": 30, "path": []interface{}{"cover.jpg"}},
+			},
+		},
+	})
+
+	meta, err := torrent.GetMetadata()
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(40), meta.Length)
+		assert.Equal(t, []FileMetadata{
+			{Path: filepath.Join("album", "cd1", "track1.flac"), Length: 10, Offset: 0},
+			{Path: filepath.Join("album", "empty.txt"), Length: 0, Offset: 10},
+			{Path: filepath.Join("album", "cover.jpg"), Length: 30, Offset: 10},
+		}, meta.Files)
+	}
+
+	trackerURL, err := torrent.TrackerURL([]byte("-GR0001-000000000000"), 6881)
+	if assert.NoError(t, err) {
+		assert.Contains(t, trackerURL, "left=40")
+	}
+}
+
+func TestInfoHashRawBytes(t *testing.T) {
+	t.Parallel()
+	// Unsorted info dictionary with keys unknown to TorrentInfo, which must be hashed as found in the file
+	info := "d4:name8:file.iso6:lengthi1e12:piece lengthi16e6:pieces20:" + strings.Repeat("a", 20) +
+		"6:md5sum32:" + strings.Repeat("0", 32) + "7:privatei1e1:xli1ei2eee"
+	path := filepath.Join(t.TempDir(), "test.torrent")
+	err := os.WriteFile(path, []byte("d8:announce19:http://example.com/4:info"+info+"e"), 0o644)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	torrent, err := TorrentFromFile(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "file.iso", torrent.Info.Name)
+	assert.True(t, torrent.Info.Private)
+	hash := sha1.Sum([]byte(info))
+	infoHash, err := torrent.InfoHash()
+	if assert.NoError(t, err) {
+		assert.Equal(t, hash[:], infoHash)
+	}
+	meta, err := torrent.GetMetadata()
+	if assert.NoError(t, err) {
+		assert.Equal(t, hex.EncodeToString(hash[:]), meta.InfoHash)
+	}
+}
+
+func TestSingleFileMetadata(t *testing.T) {
+	t.Parallel()
+	torrent := writeTestTorrent(t, map[string]interface{}{
+		"announce": "http://tracker.example.com/announce",
+		"info": map[string]interface{}{
+			"name":         "file.iso",
+			"length":       40,
+			"piece length": 32,
+			"pieces":       strings.Repeat("a", 2*20),
+		},
+	})
+
+	meta, err := torrent.GetMetadata()
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(40), meta.Length)
+		assert.Equal(t, []FileMetadata{{Path: "file.iso", Length: 40}}, meta.Files)
+	}
+
+	torrent.Info.Length = 80
+	_, err = torrent.GetMetadata()
+	assert.Error(t, err)
+}
+
+func TestFilesMetadataInvalidPaths(t *testing.T) {
+	t.Parallel()
+	for _, path := range [][]string{{}, {".."}, {"a", ""}, {"a/b"}, {"/etc"}} {
+		info := TorrentInfo{Name: "dir", Files: []FileInfo{{Length: 1, Path: path}}}
+		_, err := info.FilesMetadata()
+		assert.Error(t, err, path)
+	}
+}
+
+func TestMetainfoFields(t *testing.T) {
+	t.Parallel()
+	torrent := writeTestTorrent(t, map[string]interface{}{
+		"announce":      "http://tracker.example.com/announce",
+		"creation date": 1700000000,
+		"encoding":      "UTF-8",
+		"httpseeds":     []interface{}{"http://seed.example.com/"},
+		"nodes":         []interface{}{[]interface{}{"router.example.com", 6881}, []interface{}{"10.0.0.1", 6882}},
+		"info": map[string]interface{}{
+			"name":         "file.iso",
+			"length":       1,
+			"piece length": 16,
+			"pieces":       strings.Repeat("a", 20),
+			"private":      1,
+			"source":       "TRACKER",
+		},
+	})
+
+	meta, err := torrent.GetMetadata()
+	if assert.NoError(t, err) {
+		assert.Equal(t, time.Unix(1700000000, 0), meta.CreationDate)
+		assert.Equal(t, "UTF-8", meta.Encoding)
+		assert.Equal(t, []string{"http://seed.example.com/"}, meta.HTTPSeeds)
+		assert.True(t, meta.Private)
+		assert.Equal(t, "TRACKER", meta.Source)
+		assert.Empty(t, meta.Nodes)
+	}
+	assert.Equal(t, []Node{{Host: "router.example.com", Port: 6881}, {Host: "10.0.0.1", Port: 6882}}, torrent.Nodes)
+	assert.Equal(t, "10.0.0.1:6882", torrent.Nodes[1].String())
+	assert.True(t, torrent.AllowsPeerSource(PeerSourceTracker))
+	assert.False(t, torrent.AllowsPeerSource(PeerSourceDHT))
+	assert.False(t, torrent.AllowsPeerSource(PeerSourcePEX))
+
+	torrent.Info.Private = false
+	assert.True(t, torrent.AllowsPeerSource(PeerSourceLSD))
+	assert.Len(t, torrent.DHTNodes(), 2)
+
+	encoded, err := bencode.Marshal(torrent)
+	if assert.NoError(t, err) {
+		assert.Contains(t, string(encoded), "5:nodesll18:router.example.comi6881eel8:10.0.0.1i6882eee")
+		assert.Contains(t, string(encoded), "13:creation datei1700000000e")
+	}
+
+	var node Node
+	assert.Error(t, bencode.Unmarshal([]byte("l4:hosti0ee"), &node))
+	assert.Error(t, bencode.Unmarshal([]byte("li1ei2ee"), &node))
+}
+
+func TestSeedURLsSingleString(t *testing.T) {
+	t.Parallel()
+	info := map[string]interface{}{"name": "file.iso", "length": 1, "piece length": 16, "pieces": strings.Repeat("a", 20)}
+	torrent := writeTestTorrent(t, map[string]interface{}{"url-list": "http://seed.example.com/", "info": info})
+	assert.Equal(t, SeedURLs{"http://seed.example.com/"}, torrent.URLList)
+
+	torrent = writeTestTorrent(t, map[string]interface{}{"url-list": "", "info": info})
+	assert.Nil(t, torrent.URLList)
+
+	torrent = writeTestTorrent(t, map[string]interface{}{
+		"url-list": []interface{}{"http://a.example.com/", "http://b.example.com/"}, "info": info,
+	})
+	assert.Equal(t, SeedURLs{"http://a.example.com/", "http://b.example.com/"}, torrent.URLList)
+
+	encoded, err := bencode.Marshal(torrent)
+	if assert.NoError(t, err) {
+		assert.Contains(t, string(encoded), "8:url-listl21:http://a.example.com/21:http://b.example.com/e")
+	}
+}
@@ -1,16 +1,28 @@
 package bittorrent
 
 import (
+	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"github.com/GFLdev/gorrent/pkg/bencode"
 	"github.com/GFLdev/gorrent/pkg/utils"
+	"io"
+	"math/rand/v2"
 	"net"
 	"net/http"
 	"net/url"
 	"strconv"
 )
 
+// TrackerLimits bounds the resources used to read and decode tracker responses, which come from untrusted hosts.
+var TrackerLimits = bencode.Limits{
+	MaxDepth:        32,
+	MaxStringLength: 4 << 20, // compact peer lists
+	MaxElements:     1 << 16,
+	MaxInputSize:    8 << 20,
+}
+
 // PeersList represents the response from a tracker, containing a parsed peers list and its interval.
 type PeersList struct {
 	// Interval specifies the wait time in seconds before the next tracker request.
@@ -33,15 +45,58 @@ type failedResponse struct {
 	FailureReason string `bencode:"failure reason"`
 }
 
-// TrackerURL constructs a tracker URL with query parameters based on torrent and peer details.
+// TrackerTiers returns the tiers of tracker URLs to announce to, in the order they are tried (BEP 12). When the
+// torrent has an announce-list, its tiers are used, each shuffled on first use, and the announce key is ignored;
+// otherwise, the announce URL is the only tier.
+func (t *TorrentFile) TrackerTiers() [][]string {
+	if t.tiers != nil {
+		return t.tiers
+	}
+
+	t.tiers = make([][]string, 0, len(t.AnnounceList))
+	for _, tier := range t.AnnounceList {
+		trackers := make([]string, 0, len(tier))
+		for _, tracker := range tier {
+			if tracker != "" {
+				trackers = append(trackers, tracker)
+			}
+		}
+		if len(trackers) > 0 {
+			rand.Shuffle(len(trackers), func(i, j int) {
+				trackers[i], trackers[j] = trackers[j], trackers[i]
+			})
+			t.tiers = append(t.tiers, trackers)
+		}
+	}
+	if len(t.tiers) == 0 && t.Announce != "" {
+		t.tiers = append(t.tiers, []string{t.Announce})
+	}
+	return t.tiers
+}
+
+// TrackerURL constructs a tracker URL with query parameters based on torrent and peer details, for the first
+// tracker to be tried and the primary info hash of the torrent.
 func (t *TorrentFile) TrackerURL(id []byte, port uint16) (string, error) {
-	base, err := url.Parse(t.Announce)
+	tiers := t.TrackerTiers()
+	if len(tiers) == 0 {
+		return "", fmt.Errorf("could not get tracker url: torrent has no trackers")
+	}
+	hash, err := t.InfoHash()
+	if err != nil {
+		return "", fmt.Errorf("could not get tracker url: %w", err)
+	}
+	return t.announceURL(tiers[0][0], hash, id, port)
+}
+
+// announceURL constructs the URL of an announce request to the given tracker for the given info hash, with query
+// parameters based on torrent and peer details.
+func (t *TorrentFile) announceURL(tracker string, hash []byte, id []byte, port uint16) (string, error) {
+	base, err := url.Parse(tracker)
 	if err != nil {
 		return "", fmt.Errorf("could not parse announce url: %w", err)
 	}
 
 	// TODO: implement compact parameter
-	hash, err := t.InfoHash()
 	params := url.Values{
 		"info_hash":  []string{string(hash)},
 		"peer_id":    []string{string(id)},
@@ -49,7 +104,7 @@ func (t *TorrentFile) TrackerURL(id []byte, port uint16) (string, error) {
 		"uploaded":   []string{"0"}, // uploaded nothing yet
 		"downloaded": []string{"0"}, // downloaded nothing yet
 		"compact":    []string{"1"}, // non-compact not implemented
-		"left":       []string{strconv.Itoa(t.Info.Length)},
+		"left":       []string{strconv.FormatInt(t.Info.TotalLength(), 10)},
 	}
 	base.RawQuery = params.Encode()
 	return base.String(), nil
@@ -62,6 +117,12 @@ func (t *TorrentFile) FetchTracker(announceURL string) ([]byte, error) {
 		return nil, fmt.Errorf("error on tracker request: %w", err)
 	}
 
+	// Do not read more than the maximum response size
+	res.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(res.Body, TrackerLimits.MaxInputSize+1), res.Body}
+
 	// Read and parse response body
 	data, err := utils.ExtractResponseData(res)
 	if err != nil {
@@ -78,7 +139,7 @@ func (t *TorrentFile) ParseTrackerResponse(data []byte, timeout int) (PeersList,
 
 	// Check failure
 	failed := failedResponse{}
-	err := bencode.Unmarshal(data, &failed)
+	err := unmarshalTrackerResponse(data, &failed)
 	if err != nil {
 		return PeersList{}, fmt.Errorf("could not unmarshal tracker response: %w", err)
 	}
@@ -88,7 +149,7 @@ func (t *TorrentFile) ParseTrackerResponse(data []byte, timeout int) (PeersList,
 
 	// Get interval and peer list
 	success := successResponse{}
-	err = bencode.Unmarshal(data, &success)
+	err = unmarshalTrackerResponse(data, &success)
 	if err != nil {
 		return PeersList{}, fmt.Errorf("could not unmarshal tracker response: %w", err)
 	}
@@ -112,3 +173,83 @@ func (t *TorrentFile) ParseTrackerResponse(data []byte, timeout int) (PeersList,
 	}
 	return trackerResponse, nil
 }
+
+// FetchPeers announces to the trackers of the torrent and returns the peers list of the first one to respond,
+// following BEP 12: trackers are tried in order within each tier, falling through to the next tier when all of them
+// fail, and the tracker that responds is moved to the front of its tier, to be tried first next time.
+func (t *TorrentFile) FetchPeers(id []byte, port uint16, timeout int) (PeersList, error) {
+	var errs []error
+	for _, tier := range t.TrackerTiers() {
+		for i, tracker := range tier {
+			peers, err := t.announce(tracker, id, port, timeout)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("tracker %s: %w", tracker, err))
+				continue
+			}
+
+			// Move responding tracker to the front of its tier
+			copy(tier[1:i+1], tier[:i])
+			tier[0] = tracker
+			return peers, nil
+		}
+	}
+
+	if len(errs) == 0 {
+		return PeersList{}, fmt.Errorf("could not fetch peers: torrent has no trackers")
+	}
+	return PeersList{}, fmt.Errorf("could not fetch peers: %w", errors.Join(errs...))
+}
+
+// announce sends an announce request to the given tracker for each info hash of the torrent, as hybrid torrents are
+// announced with both their v1 and v2 identities, and merges their peers lists. It fails only if all requests fail.
+func (t *TorrentFile) announce(tracker string, id []byte, port uint16, timeout int) (PeersList, error) {
+	hashes, err := t.InfoHashes()
+	if err != nil {
+		return PeersList{}, err
+	}
+
+	var merged PeersList
+	var errs []error
+	seen := make(map[string]bool)
+	for _, hash := range hashes {
+		peers, err := t.announceHash(tracker, hash, id, port, timeout)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if merged.Interval == 0 || (peers.Interval > 0 && peers.Interval < merged.Interval) {
+			merged.Interval = peers.Interval
+		}
+		for _, peer := range peers.Peers {
+			if !seen[peer.String()] {
+				seen[peer.String()] = true
+				merged.Peers = append(merged.Peers, peer)
+			}
+		}
+	}
+	if len(errs) == len(hashes) {
+		return PeersList{}, errors.Join(errs...)
+	}
+	return merged, nil
+}
+
+// announceHash sends an announce request for the given info hash to the given tracker and parses its peers list.
+func (t *TorrentFile) announceHash(tracker string, hash, id []byte, port uint16, timeout int) (PeersList, error) {
+	announceURL, err := t.announceURL(tracker, hash, id, port)
+	if err != nil {
+		return PeersList{}, err
+	}
+	data, err := t.FetchTracker(announceURL)
+	if err != nil {
+		return PeersList{}, err
+	}
+	return t.ParseTrackerResponse(data, timeout)
+}
+
+// unmarshalTrackerResponse decodes a bencoded tracker response into v, within TrackerLimits.
+func unmarshalTrackerResponse(data []byte, v any) error {
+	dec := bencode.NewDecoder(bytes.NewReader(data))
+	dec.SetLimits(TrackerLimits)
+	return dec.Decode(v)
+}
@@ -0,0 +1,82 @@
+package bittorrent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestTracker starts a tracker responding with a single peer, or with a failure if failing is set.
+func newTestTracker(t *testing.T, failing bool) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			_, _ = w.Write([]byte("d14:failure reason4:downe"))
+			return
+		}
+		_, _ = w.Write([]byte("d8:intervali900e5:peers6:\x7f\x00\x00\x01\x1a\xe1e"))
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestFetchPeersTiers(t *testing.T) {
+	t.Parallel()
+	down1, down2 := newTestTracker(t, true), newTestTracker(t, true)
+	up := newTestTracker(t, false)
+	torrent := &TorrentFile{
+		Announce:     "http://ignored.example.com/announce",
+		AnnounceList: [][]string{{down1}, {down2, up}},
+		Info:         TorrentInfo{Name: "test", PieceLength: 1, Length: 1, Pieces: "aaaaaaaaaaaaaaaaaaaa"},
+	}
+
+	peers, err := torrent.FetchPeers([]byte("-GR0001-000000000000"), 6881, 1)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 900, peers.Interval)
+		if assert.Len(t, peers.Peers, 1) {
+			assert.Equal(t, uint16(6881), peers.Peers[0].Port)
+		}
+	}
+	assert.Equal(t, [][]string{{down1}, {up, down2}}, torrent.TrackerTiers())
+
+	torrent = &TorrentFile{AnnounceList: [][]string{{down1, down2}}}
+	_, err = torrent.FetchPeers([]byte("-GR0001-000000000000"), 6881, 1)
+	assert.ErrorContains(t, err, "down")
+}
+
+func TestTrackerTiersFallback(t *testing.T) {
+	t.Parallel()
+	torrent := &TorrentFile{Announce: "http://tracker.example.com/announce", AnnounceList: [][]string{{}, {""}}}
+	assert.Equal(t, [][]string{{"http://tracker.example.com/announce"}}, torrent.TrackerTiers())
+
+	torrent = &TorrentFile{}
+	_, err := torrent.TrackerURL([]byte("-GR0001-000000000000"), 6881)
+	assert.Error(t, err)
+}
+
+func TestFetchPeersHybrid(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	var announced []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		announced = append(announced, r.URL.Query().Get("info_hash"))
+		mu.Unlock()
+		_, _ = w.Write([]byte("d8:intervali900e5:peers6:\x7f\x00\x00\x01\x1a\xe1e"))
+	}))
+	t.Cleanup(server.Close)
+	torrent := writeTestTorrent(t, map[string]interface{}{"announce": server.URL, "info": hybridTestInfo(true)})
+
+	// Both info hashes are announced, and the peers they share are merged
+	peers, err := torrent.FetchPeers([]byte("-GR0001-000000000000"), 6881, 1)
+	if assert.NoError(t, err) {
+		assert.Len(t, peers.Peers, 1)
+	}
+	hashes, err := torrent.InfoHashes()
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{string(hashes[0]), string(hashes[1])}, announced)
+	}
+}
@@ -0,0 +1,237 @@
+package bittorrent
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"github.com/GFLdev/gorrent/pkg/bencode"
+	"maps"
+	"math/bits"
+	"slices"
+)
+
+// BlockSize is the size in bytes of the blocks hashed as leaves of the Merkle trees of v2 torrents (BEP 52).
+const BlockSize = 16 << 10
+
+// FileTree represents a node of the "file tree" dictionary of a v2 torrent (BEP 52): either a directory, mapping the
+// names of its entries to their nodes, or a file, bencoded as a dictionary holding its properties under an empty key.
+type FileTree struct {
+	// Entries maps the names of the entries of a directory to their nodes.
+	Entries map[string]*FileTree
+	// File holds the properties of the node if it is a file, or nil if it is a directory.
+	File *FileTreeFile
+}
+
+// FileTreeFile represents the properties of a file of a v2 file tree.
+type FileTreeFile struct {
+	// Length represents the size of the file in bytes.
+	Length int64 `bencode:"length"`
+	// PiecesRoot is the SHA-256 root of the Merkle tree of the file content, absent for empty files.
+	PiecesRoot string `bencode:"pieces root,omitempty"`
+	// Attr holds the attributes of the file (BEP 47).
+	Attr FileAttr `bencode:"attr,omitempty"`
+	// SymlinkPath holds the path components of the target of the file, relative to the torrent directory, if it is a
+	// symlink.
+	SymlinkPath []string `bencode:"symlink path,omitempty"`
+}
+
+// TreeFile represents a file listed by a v2 file tree, with its path.
+type TreeFile struct {
+	// Path holds the path components of the file, relative to the torrent directory, the last being the file name.
+	Path []string
+	// FileTreeFile holds the properties of the file.
+	FileTreeFile
+}
+
+// MarshalBencode encodes the node as a dictionary of its entries, or of its file properties under an empty key.
+func (ft FileTree) MarshalBencode() ([]byte, error) {
+	if ft.File != nil {
+		return bencode.Encode(map[string]*FileTreeFile{"": ft.File})
+	}
+	return bencode.Encode(ft.Entries)
+}
+
+// UnmarshalBencode decodes a node from a dictionary, which describes a file if it holds an empty key, or a directory
+// otherwise.
+func (ft *FileTree) UnmarshalBencode(data []byte) error {
+	var entries map[string]bencode.RawMessage
+	err := bencode.Unmarshal(data, &entries)
+	if err != nil {
+		return fmt.Errorf("could not parse file tree: %w", err)
+	}
+
+	if raw, ok := entries[""]; ok {
+		if len(entries) != 1 {
+			return fmt.Errorf("could not parse file tree: file node with other entries")
+		}
+		ft.File = &FileTreeFile{}
+		return bencode.Unmarshal(raw, ft.File)
+	}
+
+	ft.Entries = make(map[string]*FileTree, len(entries))
+	for name, raw := range entries {
+		child := &FileTree{}
+		if err = child.UnmarshalBencode(raw); err != nil {
+			return err
+		}
+		ft.Entries[name] = child
+	}
+	return nil
+}
+
+// Files lists the files of the tree, with their paths relative to it, in the order their content is laid out in
+// pieces: depth first, with the entries of each directory sorted by name.
+func (ft *FileTree) Files() []TreeFile {
+	var files []TreeFile
+	var walk func(node *FileTree, path []string)
+	walk = func(node *FileTree, path []string) {
+		if node.File != nil {
+			files = append(files, TreeFile{Path: path, FileTreeFile: *node.File})
+			return
+		}
+		for _, name := range slices.Sorted(maps.Keys(node.Entries)) {
+			walk(node.Entries[name], append(slices.Clip(path), name))
+		}
+	}
+	walk(ft, nil)
+	return files
+}
+
+// IsV2 reports whether the info dictionary describes its content with a v2 file tree (BEP 52).
+func (info *TorrentInfo) IsV2() bool {
+	return info.MetaVersion == 2
+}
+
+// IsV1 reports whether the info dictionary describes its content with v1 SHA-1 pieces, either alone or along with a
+// v2 file tree.
+func (info *TorrentInfo) IsV1() bool {
+	return !info.IsV2() || info.Pieces != ""
+}
+
+// InfoHashV2 generates and returns the SHA-256 hash of the bencoded info dictionary of a v2 torrent (BEP 52). For
+// torrents parsed from a file, the hash is computed over the exact bytes of the info dictionary found in the file.
+func (t *TorrentFile) InfoHashV2() ([]byte, error) {
+	if !t.Info.IsV2() {
+		return nil, fmt.Errorf("could not calculate v2 info hash: torrent is not v2")
+	}
+	info, err := t.infoBytes()
+	if err != nil {
+		return nil, fmt.Errorf("could not calculate v2 info hash: %w", err)
+	}
+	hash := sha256.Sum256(info)
+	return hash[:], nil
+}
+
+// VerifyPieceLayers checks that the piece layers of a v2 torrent hash to the pieces roots of their files. Every file
+// longer than a piece must have its layer, holding the SHA-256 hashes of its pieces.
+func (t *TorrentFile) VerifyPieceLayers() error {
+	pieceLength := t.Info.PieceLength
+	if pieceLength < BlockSize || pieceLength&(pieceLength-1) != 0 {
+		return fmt.Errorf("invalid v2 piece length %d", pieceLength)
+	}
+	if t.Info.FileTree == nil {
+		return fmt.Errorf("missing file tree")
+	}
+
+	for _, file := range t.Info.FileTree.Files() {
+		if file.Length == 0 {
+			continue
+		}
+		if len(file.PiecesRoot) != sha256.Size {
+			return fmt.Errorf("invalid pieces root of file %v", file.Path)
+		}
+		if file.Length <= int64(pieceLength) {
+			continue
+		}
+
+		layer, ok := t.PieceLayers[file.PiecesRoot]
+		if !ok {
+			return fmt.Errorf("missing piece layer of file %v", file.Path)
+		}
+		numPieces := (file.Length + int64(pieceLength) - 1) / int64(pieceLength)
+		if int64(len(layer)) != numPieces*sha256.Size {
+			return fmt.Errorf("invalid piece layer of file %v: expected %d hashes", file.Path, numPieces)
+		}
+		root := PieceLayerRoot([]byte(layer), pieceLength)
+		if !bytes.Equal(root, []byte(file.PiecesRoot)) {
+			return fmt.Errorf("piece layer of file %v does not match its pieces root", file.Path)
+		}
+	}
+	return nil
+}
+
+// MerkleRoot returns the pieces root of a file of a v2 torrent: the root of the Merkle tree whose leaves are the
+// SHA-256 hashes of the 16 KiB blocks of data, padded with zero hashes to a power of two.
+func MerkleRoot(data []byte) []byte {
+	leaves := blockHashes(data)
+	root := merkleRoot(leaves, nextPowerOfTwo(len(leaves)), [sha256.Size]byte{})
+	return root[:]
+}
+
+// PieceHashV2 returns the hash of a piece of a v2 torrent, as found in piece layers: the root of the Merkle subtree
+// of the blocks of the piece, padded with zero hashes to the piece length. The last piece of a file may be shorter.
+func PieceHashV2(piece []byte, pieceLength int) []byte {
+	root := merkleRoot(blockHashes(piece), pieceLength/BlockSize, [sha256.Size]byte{})
+	return root[:]
+}
+
+// PieceLayerRoot returns the root of the Merkle tree of a file from its piece layer, the concatenated hashes of its
+// pieces, padding it to a power of two with the hashes of pieces made of zero hashes.
+func PieceLayerRoot(layer []byte, pieceLength int) []byte {
+	hashes := make([][sha256.Size]byte, len(layer)/sha256.Size)
+	for i := range hashes {
+		copy(hashes[i][:], layer[i*sha256.Size:])
+	}
+	root := merkleRoot(hashes, nextPowerOfTwo(len(hashes)), paddingHash(pieceLength/BlockSize))
+	return root[:]
+}
+
+// blockHashes returns the SHA-256 hashes of the blocks of data, the last block possibly being shorter.
+func blockHashes(data []byte) [][sha256.Size]byte {
+	hashes := make([][sha256.Size]byte, 0, (len(data)+BlockSize-1)/BlockSize)
+	for start := 0; start < len(data); start += BlockSize {
+		hashes = append(hashes, sha256.Sum256(data[start:min(start+BlockSize, len(data))]))
+	}
+	return hashes
+}
+
+// merkleRoot returns the root of the Merkle tree of the given leaves, filled with pad up to width leaves, a power of
+// two.
+func merkleRoot(leaves [][sha256.Size]byte, width int, pad [sha256.Size]byte) [sha256.Size]byte {
+	layer := make([][sha256.Size]byte, max(width, 1))
+	copy(layer, leaves)
+	for i := len(leaves); i < len(layer); i++ {
+		layer[i] = pad
+	}
+
+	var pair [2 * sha256.Size]byte
+	for len(layer) > 1 {
+		for i := range len(layer) / 2 {
+			copy(pair[:], layer[2*i][:])
+			copy(pair[sha256.Size:], layer[2*i+1][:])
+			layer[i] = sha256.Sum256(pair[:])
+		}
+		layer = layer[:len(layer)/2]
+	}
+	return layer[0]
+}
+
+// paddingHash returns the root of a Merkle tree of the given number of zero leaves, a power of two.
+func paddingHash(leaves int) [sha256.Size]byte {
+	var hash [sha256.Size]byte
+	var pair [2 * sha256.Size]byte
+	for ; leaves > 1; leaves /= 2 {
+		copy(pair[:], hash[:])
+		copy(pair[sha256.Size:], hash[:])
+		hash = sha256.Sum256(pair[:])
+	}
+	return hash
+}
+
+// nextPowerOfTwo returns the smallest power of two greater than or equal to n, and at least 1.
+func nextPowerOfTwo(n int) int {
+	if n <= 1 {
+		return 1
+	}
+	return 1 << bits.Len(uint(n-1))
+}
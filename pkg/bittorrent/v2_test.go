@@ -0,0 +1,224 @@
+package bittorrent
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+
+	"github.com/GFLdev/gorrent/pkg/bencode"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerkleRoot(t *testing.T) {
+	t.Parallel()
+	block := bytes.Repeat([]byte{1}, BlockSize)
+	blockHash := sha256.Sum256(block)
+	assert.Equal(t, blockHash[:], MerkleRoot(block))
+
+	// Three blocks are padded with a zero hash to four leaves
+	data := append(bytes.Repeat(block, 2), 2)
+	lastHash := sha256.Sum256([]byte{2})
+	left := sha256.Sum256(append(blockHash[:], blockHash[:]...))
+	right := sha256.Sum256(append(lastHash[:], make([]byte, sha256.Size)...))
+	root := sha256.Sum256(append(left[:], right[:]...))
+	assert.Equal(t, root[:], MerkleRoot(data))
+
+	// The piece layer of two-block pieces hashes to the same root
+	layer := append(PieceHashV2(data[:2*BlockSize], 2*BlockSize), PieceHashV2(data[2*BlockSize:], 2*BlockSize)...)
+	assert.Equal(t, left[:], layer[:sha256.Size])
+	assert.Equal(t, right[:], layer[sha256.Size:])
+	assert.Equal(t, root[:], PieceLayerRoot(layer, 2*BlockSize))
+}
+
+func TestV2Metadata(t *testing.T) {
+	t.Parallel()
+	video := bytes.Repeat([]byte("video"), 10000)
+	notes := []byte("notes")
+	videoRoot := string(MerkleRoot(video))
+	var layer []byte
+	for start := 0; start < len(video); start += BlockSize {
+		layer = append(layer, PieceHashV2(video[start:min(start+BlockSize, len(video))], BlockSize)...)
+	}
+
+	info := map[string]interface{}{
+		"name":         "movie",
+		"piece length": BlockSize,
+		"meta version": 2,
+		"file tree": map[string]interface{}{
+			"video.mkv": map[string]interface{}{
+				"": map[string]interface{}{"length": len(video), "pieces root": videoRoot},
+			},
+			"extras": map[string]interface{}{
+				"notes.txt": map[string]interface{}{
+					"": map[string]interface{}{"length": len(notes), "pieces root": string(MerkleRoot(notes))},
+				},
+			},
+		},
+	}
+	torrent := writeTestTorrent(t, map[string]interface{}{
+		"announce":     "http://tracker.example.com/announce",
+		"info":         info,
+		"piece layers": map[string]interface{}{videoRoot: string(layer)},
+	})
+
+	meta, err := torrent.GetMetadata()
+	if assert.NoError(t, err) {
+		rawInfo, _ := bencode.Marshal(info)
+		hash := sha256.Sum256(rawInfo)
+		assert.Equal(t, 2, meta.MetaVersion)
+		assert.Equal(t, hex.EncodeToString(hash[:]), meta.InfoHashV2)
+		assert.Equal(t, hex.EncodeToString(hash[:20]), meta.InfoHash)
+		assert.Equal(t, int64(len(video)+len(notes)), meta.Length)
+		assert.Empty(t, meta.PieceHashes)
+		assert.Equal(t, []FileMetadata{
+			{Path: filepath.Join("movie", "extras", "notes.txt"), Length: 5, PiecesRoot: string(MerkleRoot(notes))},
+			{Path: filepath.Join("movie", "video.mkv"), Length: int64(len(video)), Offset: 16384, PiecesRoot: videoRoot},
+		}, meta.Files)
+
+		// The piece map lays out files at the offsets of the metadata
+		m, err := NewPieceMap(&meta)
+		if assert.NoError(t, err) {
+			segments, err := m.Piece(1)
+			if assert.NoError(t, err) {
+				assert.Equal(t, []FileSegment{{File: 1, Offset: 0, Length: 16384}}, segments)
+			}
+		}
+	}
+
+	magnet, err := torrent.Magnet()
+	if assert.NoError(t, err) {
+		assert.Nil(t, magnet.InfoHash)
+		assert.Len(t, magnet.InfoHashV2, sha256.Size)
+	}
+
+	// Tampered layers do not match the pieces root
+	layer[0] ^= 1
+	torrent.PieceLayers[videoRoot] = string(layer)
+	_, err = torrent.GetMetadata()
+	assert.ErrorContains(t, err, "does not match its pieces root")
+
+	delete(torrent.PieceLayers, videoRoot)
+	_, err = torrent.GetMetadata()
+	assert.ErrorContains(t, err, "missing piece layer")
+}
+
+func TestV2SingleFile(t *testing.T) {
+	t.Parallel()
+	data := []byte("hello")
+	torrent := writeTestTorrent(t, map[string]interface{}{
+		"info": map[string]interface{}{
+			"name":         "hello.txt",
+			"piece length": BlockSize,
+			"meta version": 2,
+			"file tree": map[string]interface{}{
+				"hello.txt": map[string]interface{}{
+					"": map[string]interface{}{"length": len(data), "pieces root": string(MerkleRoot(data))},
+				},
+			},
+		},
+	})
+
+	meta, err := torrent.GetMetadata()
+	if assert.NoError(t, err) {
+		assert.Equal(t, []FileMetadata{{Path: "hello.txt", Length: 5, PiecesRoot: string(MerkleRoot(data))}}, meta.Files)
+	}
+
+	// The file tree survives a round trip
+	encoded, err := bencode.Marshal(&torrent.Info)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []byte(torrent.rawInfo), encoded)
+	}
+}
+
+// hybridTestInfo returns the info dictionary of a hybrid torrent with two files, the first one followed by a padding
+// file aligning the second one to a piece boundary, or not if pad is false.
+func hybridTestInfo(pad bool) map[string]interface{} {
+	first, second := bytes.Repeat([]byte{1}, 100), bytes.Repeat([]byte{2}, 50)
+	files := []interface{}{map[string]interface{}{"length": len(first), "path": []interface{}{"first.bin"}}}
+	if pad {
+		files = append(files, map[string]interface{}{
+			"length": BlockSize - len(first), "path": []interface{}{".pad", "16284"}, "attr": "p",
+		})
+	}
+	files = append(files, map[string]interface{}{"length": len(second), "path": []interface{}{"second.bin"}})
+
+	return map[string]interface{}{
+		"name":         "hybrid",
+		"piece length": BlockSize,
+		"pieces":       string(bytes.Repeat([]byte{3}, 2*20)),
+		"files":        files,
+		"meta version": 2,
+		"file tree": map[string]interface{}{
+			"first.bin": map[string]interface{}{
+				"": map[string]interface{}{"length": len(first), "pieces root": string(MerkleRoot(first))},
+			},
+			"second.bin": map[string]interface{}{
+				"": map[string]interface{}{"length": len(second), "pieces root": string(MerkleRoot(second))},
+			},
+		},
+	}
+}
+
+func TestHybridMetadata(t *testing.T) {
+	t.Parallel()
+	torrent := writeTestTorrent(t, map[string]interface{}{"info": hybridTestInfo(true)})
+
+	meta, err := torrent.GetMetadata()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 2, meta.MetaVersion)
+	assert.Len(t, meta.PieceHashes, 2)
+	assert.Equal(t, int64(150), meta.Length)
+	if assert.Len(t, meta.Files, 3) {
+		assert.NotEmpty(t, meta.Files[0].PiecesRoot)
+		assert.True(t, meta.Files[1].Padding)
+		assert.Empty(t, meta.Files[1].PiecesRoot)
+		assert.Equal(t, int64(BlockSize), meta.Files[2].Offset)
+		assert.NotEmpty(t, meta.Files[2].PiecesRoot)
+	}
+
+	// Peers may use either info hash
+	hashes, err := torrent.InfoHashes()
+	if assert.NoError(t, err) && assert.Len(t, hashes, 2) {
+		assert.Equal(t, meta.InfoHash, hex.EncodeToString(hashes[0]))
+		assert.Equal(t, meta.InfoHashV2[:40], hex.EncodeToString(hashes[1]))
+		for _, hash := range hashes {
+			matched, err := torrent.VerifyHandshake(NewHandshake(hash, []byte("-GR0001-000000000000")))
+			if assert.NoError(t, err) {
+				assert.Equal(t, hash, matched)
+			}
+		}
+	}
+	_, err = torrent.VerifyHandshake(NewHandshake(make([]byte, 20), []byte("-GR0001-000000000000")))
+	assert.ErrorContains(t, err, "does not match the torrent")
+
+	magnet, err := torrent.Magnet()
+	if assert.NoError(t, err) {
+		assert.Equal(t, hashes[0], magnet.InfoHash)
+		assert.Equal(t, meta.InfoHashV2, hex.EncodeToString(magnet.InfoHashV2))
+	}
+
+	// Padding files are not left to download
+	torrent.Announce = "http://tracker.example.com/announce"
+	trackerURL, err := torrent.TrackerURL([]byte("-GR0001-000000000000"), 6881)
+	if assert.NoError(t, err) {
+		assert.Contains(t, trackerURL, "left=150")
+	}
+}
+
+func TestHybridMisaligned(t *testing.T) {
+	t.Parallel()
+	info := hybridTestInfo(false)
+	info["pieces"] = string(bytes.Repeat([]byte{3}, 20))
+	torrent := writeTestTorrent(t, map[string]interface{}{"info": info})
+
+	_, err := torrent.GetMetadata()
+	assert.ErrorContains(t, err, "not aligned to a piece boundary")
+
+	torrent.Info.Files[1].Path = []string{"other.bin"}
+	_, err = torrent.GetMetadata()
+	assert.ErrorContains(t, err, "does not match the file tree")
+}